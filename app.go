@@ -1,6 +1,8 @@
 package common
 
 import (
+	"context"
+	"net/http"
 	"strings"
 
 	"github.com/aluka-7/utils"
@@ -92,3 +94,42 @@ func (av AppVersion) CompareVersion(version string) int {
 		}
 	}
 }
+
+// 传递App客户端版本信息的HTTP头
+const (
+	DeviceHeader  = "X-App-Device"
+	VersionHeader = "X-App-Version"
+	NetworkHeader = "X-App-Network"
+)
+
+type appVersionKey struct{}
+
+// WithAppVersion 将App客户端的版本信息保存到上下文中，调用其他服务时Client会通过HTTP头继续传递
+func WithAppVersion(ctx context.Context, av AppVersion) context.Context {
+	return context.WithValue(ctx, appVersionKey{}, av)
+}
+
+// AppVersionFromContext 获取上下文中App客户端的版本信息
+func AppVersionFromContext(ctx context.Context) (AppVersion, bool) {
+	av, ok := ctx.Value(appVersionKey{}).(AppVersion)
+	return av, ok
+}
+
+// AppVersionFromRequest 从请求头解析App客户端的版本信息，请求没有携带任何版本信息时返回false
+func AppVersionFromRequest(r *http.Request) (AppVersion, bool) {
+	av := NewAppVersion(r.Header.Get(DeviceHeader), r.Header.Get(VersionHeader), r.Header.Get(NetworkHeader))
+	return av, av != AppVersion{}
+}
+
+// setHeader 将版本信息写入HTTP头
+func (av AppVersion) setHeader(h http.Header) {
+	if av.device != "" {
+		h.Set(DeviceHeader, av.device)
+	}
+	if av.version != "" {
+		h.Set(VersionHeader, av.version)
+	}
+	if av.network != "" {
+		h.Set(NetworkHeader, av.network)
+	}
+}
@@ -0,0 +1,118 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrUnknownCallback            = errors.New("未注册的回调类型")
+	ErrUnsupportedCallbackVersion = errors.New("不支持的回调数据版本")
+)
+
+// CallbackUpgrader 将回调数据从一个版本升级到下一个版本
+type CallbackUpgrader func(data json.RawMessage) (json.RawMessage, error)
+
+// CallbackRegistry 回调类型注册表，将回调类型和数据版本映射到具体的数据类型和处理函数。
+type CallbackRegistry struct {
+	mu       sync.RWMutex
+	handlers map[int]map[int]callbackHandler  // 回调类型 -> 版本 -> 处理函数
+	upgrades map[int]map[int]CallbackUpgrader // 回调类型 -> 起始版本 -> 升级到下一版本的函数
+}
+
+type callbackHandler func(ctx context.Context, cb Callback) error
+
+// NewCallbackRegistry 创建回调类型注册表
+func NewCallbackRegistry() *CallbackRegistry {
+	return &CallbackRegistry{
+		handlers: make(map[int]map[int]callbackHandler),
+		upgrades: make(map[int]map[int]CallbackUpgrader),
+	}
+}
+
+// RegisterCallback 注册回调类型指定版本的数据类型和处理函数，分发时Data会解码为T再调用处理函数，重复注册时panic。
+// @reg 回调类型注册表
+// @cbType 回调类型代码
+// @version 数据结构版本，从1开始
+// @handler 处理函数
+func RegisterCallback[T any](reg *CallbackRegistry, cbType, version int, handler func(ctx context.Context, cb Callback, payload T) error) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	versions, ok := reg.handlers[cbType]
+	if !ok {
+		versions = make(map[int]callbackHandler)
+		reg.handlers[cbType] = versions
+	}
+	if _, ok := versions[version]; ok {
+		panic(fmt.Sprintf("回调类型[%d]版本[%d]重复注册", cbType, version))
+	}
+	versions[version] = func(ctx context.Context, cb Callback) error {
+		var payload T
+		if err := Codec().Unmarshal([]byte(cb.Data), &payload); err != nil {
+			return WrapError(fmt.Errorf("解码回调[%d]数据: %w", cb.CbType, err), ErrCodeBadRequest)
+		}
+		return handler(ctx, cb, payload)
+	}
+}
+
+// Upgrade 注册回调数据从from版本升级到from+1版本的函数，分发时如果没有对应版本的处理函数会逐级升级，重复注册时panic。
+func (reg *CallbackRegistry) Upgrade(cbType, from int, fn CallbackUpgrader) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	versions, ok := reg.upgrades[cbType]
+	if !ok {
+		versions = make(map[int]CallbackUpgrader)
+		reg.upgrades[cbType] = versions
+	}
+	if _, ok := versions[from]; ok {
+		panic(fmt.Sprintf("回调类型[%d]版本[%d]的升级函数重复注册", cbType, from))
+	}
+	versions[from] = fn
+}
+
+// Dispatch 将回调数据解码为注册的类型并调用处理函数，回调类型未注册时返回ErrUnknownCallback，
+// 版本没有处理函数且无法升级到有处理函数的版本时返回ErrUnsupportedCallbackVersion，两者都以ErrCodeBadRequest包装。
+func (reg *CallbackRegistry) Dispatch(ctx context.Context, cb Callback) error {
+	if cb.Version <= 0 {
+		cb.Version = 1
+	}
+	handler, chain, err := reg.resolve(cb.CbType, cb.Version)
+	if err != nil {
+		return err
+	}
+	for _, upgrade := range chain {
+		data, err := upgrade(json.RawMessage(cb.Data))
+		if err != nil {
+			return WrapError(fmt.Errorf("升级回调[%d]版本[%d]: %w", cb.CbType, cb.Version, err), ErrCodeBadRequest)
+		}
+		cb.Data, cb.Version = string(data), cb.Version+1
+	}
+	if cb.Opt != "" && OptFromContext(ctx) == "" {
+		ctx = WithOpt(ctx, cb.Opt)
+	}
+	return handler(ctx, cb)
+}
+
+// resolve 在读锁内查找处理函数和需要依次执行的升级函数，升级函数在释放锁后执行，避免其中再访问注册表时死锁
+func (reg *CallbackRegistry) resolve(cbType, version int) (callbackHandler, []CallbackUpgrader, error) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	versions, ok := reg.handlers[cbType]
+	if !ok {
+		return nil, nil, WrapError(fmt.Errorf("%w[%d]", ErrUnknownCallback, cbType), ErrCodeBadRequest)
+	}
+	var chain []CallbackUpgrader
+	for v := version; ; v++ {
+		if handler, ok := versions[v]; ok {
+			return handler, chain, nil
+		}
+		upgrade, ok := reg.upgrades[cbType][v]
+		if !ok {
+			return nil, nil, WrapError(fmt.Errorf("%w[%d:%d]", ErrUnsupportedCallbackVersion, cbType, version), ErrCodeBadRequest)
+		}
+		chain = append(chain, upgrade)
+	}
+}
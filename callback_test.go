@@ -0,0 +1,92 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type orderPaidV1 struct {
+	OrderId int64 `json:"orderId"`
+	Amount  int64 `json:"amount"` // 分
+}
+
+type orderPaidV2 struct {
+	OrderId  int64  `json:"orderId"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// decodeCountingCodec 统计解码次数的编解码器
+type decodeCountingCodec struct {
+	common.StdJSONCodec
+	decodes int32
+}
+
+func (c *decodeCountingCodec) Unmarshal(data []byte, v interface{}) error {
+	atomic.AddInt32(&c.decodes, 1)
+	return c.StdJSONCodec.Unmarshal(data, v)
+}
+
+func TestCallbackRegistry(t *testing.T) {
+	Convey("Test Callback Registry", t, func() {
+		reg := common.NewCallbackRegistry()
+		var got orderPaidV2
+		var opt string
+		common.RegisterCallback(reg, 1, 2, func(ctx context.Context, cb common.Callback, payload orderPaidV2) error {
+			got, opt = payload, common.OptFromContext(ctx)
+			return nil
+		})
+		reg.Upgrade(1, 1, func(data json.RawMessage) (json.RawMessage, error) {
+			var v1 orderPaidV1
+			if err := json.Unmarshal(data, &v1); err != nil {
+				return nil, err
+			}
+			return json.Marshal(orderPaidV2{OrderId: v1.OrderId, Amount: v1.Amount, Currency: "CNY"})
+		})
+
+		Convey("Test typed dispatch", func() {
+			cb := common.NewVersionedCallBack(1, 2, orderPaidV2{7, 100, "USD"})
+			cb.Opt = "op-1"
+			So(reg.Dispatch(context.Background(), cb), ShouldBeNil)
+			So(got, ShouldResemble, orderPaidV2{7, 100, "USD"})
+			So(opt, ShouldEqual, "op-1")
+		})
+		Convey("Test legacy payload is upgraded", func() {
+			So(reg.Dispatch(context.Background(), common.NewCallBack(1, orderPaidV1{7, 100})), ShouldBeNil)
+			So(got, ShouldResemble, orderPaidV2{7, 100, "CNY"})
+		})
+		Convey("Test unknown type and version", func() {
+			err := reg.Dispatch(context.Background(), common.NewCallBack(9, nil))
+			So(errors.Is(err, common.ErrUnknownCallback), ShouldBeTrue)
+			So(common.ResultFromError(err).Code, ShouldEqual, common.ErrCodeBadRequest.Code())
+			err = reg.Dispatch(context.Background(), common.NewVersionedCallBack(1, 3, nil))
+			So(errors.Is(err, common.ErrUnsupportedCallbackVersion), ShouldBeTrue)
+		})
+		Convey("Test upgraders run without holding the registry lock", func() {
+			common.RegisterCallback(reg, 2, 2, func(ctx context.Context, cb common.Callback, payload orderPaidV2) error { return nil })
+			reg.Upgrade(2, 1, func(data json.RawMessage) (json.RawMessage, error) {
+				reg.Upgrade(2, 5, func(data json.RawMessage) (json.RawMessage, error) { return data, nil })
+				return data, nil
+			})
+			So(reg.Dispatch(context.Background(), common.NewCallBack(2, orderPaidV2{})), ShouldBeNil)
+		})
+		Convey("Test payload is decoded with the current codec", func() {
+			c := &decodeCountingCodec{}
+			common.SetJSONCodec(c)
+			defer common.SetJSONCodec(common.StdJSONCodec{})
+			So(reg.Dispatch(context.Background(), common.NewVersionedCallBack(1, 2, orderPaidV2{7, 100, "USD"})), ShouldBeNil)
+			So(atomic.LoadInt32(&c.decodes), ShouldEqual, 1)
+		})
+		Convey("Test duplicate registration panics", func() {
+			So(func() {
+				common.RegisterCallback(reg, 1, 2, func(ctx context.Context, cb common.Callback, payload orderPaidV2) error { return nil })
+			}, ShouldPanic)
+		})
+	})
+}
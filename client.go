@@ -0,0 +1,280 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultClientTimeout = 10 * time.Second
+	defaultRetryBackoff  = 100 * time.Millisecond
+	defaultMaxBackoff    = 2 * time.Second
+	maxErrorBody         = 4096 // 非标准响应读取的最大长度，只用于错误信息
+)
+
+type tokenKey struct{}
+
+// WithToken 将调用方的JWT token保存到上下文中，Client未配置Token函数时会将其转发给被调用的服务
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFromContext 获取上下文中的JWT token
+func TokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey{}).(string)
+	return token
+}
+
+// ForwardContext 将请求携带的操作编号、JWT token和App版本信息保存到上下文中，使用返回的上下文调用其他服务时会继续传递
+func ForwardContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if opt := r.Header.Get(OptHeader); OptFromContext(ctx) == "" && validOpt(opt) {
+		ctx = WithOpt(ctx, opt)
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		ctx = WithToken(ctx, strings.TrimPrefix(auth, "Bearer "))
+	}
+	if av, ok := AppVersionFromRequest(r); ok {
+		ctx = WithAppVersion(ctx, av)
+	}
+	return ctx
+}
+
+// Client 调用返回Result格式结果的服务的HTTP客户端，解码返回结果并将失败的结果转换为*Error。
+// 请求会携带上下文中的操作编号、语言偏好、JWT token和App版本信息。
+type Client struct {
+	BaseURL    string                                    // 服务地址，请求路径拼接在其后
+	HTTPClient *http.Client                              // 底层的HTTP客户端
+	Header     http.Header                               // 每个请求都附带的HTTP头
+	Token      func(ctx context.Context) (string, error) // 获取调用使用的token，如jwt.TokenSource.Token，为nil时转发上下文中的token
+	MaxRetries int                                       // 幂等请求(GET、HEAD、PUT、DELETE、OPTIONS)失败后的最大重试次数，默认不重试
+	Backoff    time.Duration                             // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff time.Duration                             // 重试等待时间的上限
+	Policy     *StatusPolicy                             // 判断失败的结果能否重试的映射策略，为nil时使用全局策略，应与被调用服务一致
+}
+
+// NewClient 创建调用给定服务地址的客户端，默认超时10秒，不重试
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: defaultClientTimeout},
+		Backoff:    defaultRetryBackoff,
+		MaxBackoff: defaultMaxBackoff,
+	}
+}
+
+// clientEnvelope DtoResult和PageResult的公共结构，data延迟解码到调用方给定的类型
+type clientEnvelope struct {
+	Result
+	Pagination *Page           `json:"pagination"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Get 发起GET请求，成功时将Data解码到out，out为nil时忽略Data
+func (c *Client) Get(ctx context.Context, path string, out interface{}) error {
+	return c.Do(ctx, http.MethodGet, path, nil, out)
+}
+
+// Post 发起POST请求，body序列化为JSON作为请求体
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPost, path, body, out)
+}
+
+// Put 发起PUT请求，body序列化为JSON作为请求体
+func (c *Client) Put(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPut, path, body, out)
+}
+
+// Delete 发起DELETE请求
+func (c *Client) Delete(ctx context.Context, path string, out interface{}) error {
+	return c.Do(ctx, http.MethodDelete, path, nil, out)
+}
+
+// GetPage 发起GET请求获取分页结果，成功时将List解码到list并返回分页信息
+func (c *Client) GetPage(ctx context.Context, path string, list interface{}) (Page, error) {
+	env, err := c.do(ctx, http.MethodGet, path, nil, list)
+	if err != nil || env.Pagination == nil {
+		return Page{}, err
+	}
+	return *env.Pagination, nil
+}
+
+// Do 发起请求并解码返回结果，Code表示失败时返回*Error(包含错误码、消息和被调用服务的操作编号)，
+// 成功时将Data解码到out。响应不是Result格式时(如网关返回的错误页)使用HTTP状态码作为错误码。
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	_, err := c.do(ctx, method, path, body, out)
+	return err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) (*clientEnvelope, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = Codec().Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	retries := 0
+	if idempotent(method) {
+		retries = c.MaxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		env, err := c.roundTrip(ctx, method, path, payload)
+		if err == nil {
+			if out != nil && len(env.Data) > 0 {
+				if err = Codec().Unmarshal(env.Data, out); err != nil {
+					return nil, fmt.Errorf("解码[%s %s]返回的数据: %w", method, path, err)
+				}
+			}
+			return env, nil
+		}
+		if attempt >= retries || ctx.Err() != nil || !c.retryable(err) {
+			return nil, err
+		}
+		timer := time.NewTimer(c.backoff(attempt + 1))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, method, path string, payload []byte) (*clientEnvelope, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.setHeader(ctx, req); err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", MIMEJSON)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	mime := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	if mime == MIMEProblem {
+		var p Problem
+		if err = Codec().Unmarshal(data, &p); err == nil && p.Code != 0 {
+			return nil, clientError(p.Result(), resp.StatusCode)
+		}
+	}
+	env := new(clientEnvelope)
+	if mime != MIMEJSON || Codec().Unmarshal(data, env) != nil || env.Code == 0 {
+		if len(data) > maxErrorBody {
+			data = data[:maxErrorBody]
+		}
+		code, msg := ErrCodeInternal.code, ErrCodeInternal.message
+		if resp.StatusCode >= http.StatusBadRequest {
+			code, msg = resp.StatusCode, http.StatusText(resp.StatusCode)
+			if c, ok := LookupCode(code); ok {
+				msg = c.message
+			}
+		}
+		e := newError(code, msg, fmt.Errorf("[%s %s]返回状态码%d: %s", method, path, resp.StatusCode, data))
+		e.HTTPStatus = resp.StatusCode
+		return nil, e
+	}
+	if env.Code != CodeOK.code {
+		return nil, clientError(env.Result, resp.StatusCode)
+	}
+	return env, nil
+}
+
+// clientError 将被调用服务返回的失败结果转换为*Error，HTTPStatus使用被调用服务实际返回的状态码
+// 被调用服务返回了成功的错误码时同样转换为错误，避免异常的上游导致调用方panic
+func clientError(r Result, httpStatus int) *Error {
+	e := newError(r.Code, r.Message, nil)
+	e.Opt = r.Opt
+	e.HTTPStatus = httpStatus
+	return e
+}
+
+func (c *Client) setHeader(ctx context.Context, req *http.Request) error {
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", MIMEJSON)
+	if opt := OptFromContext(ctx); opt != "" {
+		req.Header.Set(OptHeader, opt)
+	}
+	if langs := LanguagesFromContext(ctx); len(langs) > 0 {
+		req.Header.Set("Accept-Language", strings.Join(langs, ","))
+	}
+	if av, ok := AppVersionFromContext(ctx); ok {
+		av.setHeader(req.Header)
+	}
+	token := TokenFromContext(ctx)
+	if c.Token != nil {
+		var err error
+		if token, err = c.Token(ctx); err != nil {
+			return err
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// backoff 计算第attempt次重试前的等待时间，在指数退避的基础上随机抖动
+func (c *Client) backoff(attempt int) time.Duration {
+	base, max := c.Backoff, c.MaxBackoff
+	if base <= 0 {
+		base = defaultRetryBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	wait := base << uint(attempt-1)
+	if wait <= 0 || wait > max {
+		wait = max
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryable 判断失败是否可以重试：网络错误可以重试，失败的结果按映射策略判断
+func (c *Client) retryable(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return true
+	}
+	return c.policy().Retryable(e.Code)
+}
+
+func (c *Client) policy() *StatusPolicy {
+	if c.Policy != nil {
+		return c.Policy
+	}
+	return CurrentStatusPolicy()
+}
@@ -0,0 +1,118 @@
+package common_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClient(t *testing.T) {
+	Convey("Test Client", t, func() {
+		var (
+			calls   int32
+			headers http.Header
+		)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/users/1", func(w http.ResponseWriter, r *http.Request) {
+			headers = r.Header.Clone()
+			_ = common.WriteOK(w, r, testUser{Id: 1, Name: "张三"})
+		})
+		mux.HandleFunc("/users/2", func(w http.ResponseWriter, r *http.Request) {
+			_ = common.WriteFail(w, r.WithContext(common.WithOpt(r.Context(), "opt-b")), common.NewError(errCodeUserNotFound, 2))
+		})
+		mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+			p := common.NewPagination(1, 10, 0)
+			p.SetTotalRecord(2)
+			_ = common.WritePage(w, r, []testUser{{Id: 1}, {Id: 2}}, p)
+		})
+		mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+				return
+			}
+			_ = common.WriteOK(w, r, "ok")
+		})
+		mux.HandleFunc("/bad-problem", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", common.MIMEProblem)
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"status":502,"code":200,"msg":"成功"}`))
+		})
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+		c := common.NewClient(srv.URL)
+		c.Backoff = time.Millisecond
+
+		Convey("Test data is decoded and headers propagated", func() {
+			ctx := common.WithOpt(context.Background(), "opt-a")
+			ctx = common.WithToken(ctx, "caller-token")
+			ctx = common.WithAppVersion(ctx, common.NewAppVersion("iOS-Native", "2.3.1", "4g"))
+			ctx = common.WithLanguages(ctx, []string{"en"})
+			var u testUser
+			So(c.Get(ctx, "/users/1", &u), ShouldBeNil)
+			So(u, ShouldResemble, testUser{Id: 1, Name: "张三"})
+			So(headers.Get(common.OptHeader), ShouldEqual, "opt-a")
+			So(headers.Get("Authorization"), ShouldEqual, "Bearer caller-token")
+			So(headers.Get(common.VersionHeader), ShouldEqual, "2.3.1")
+			So(headers.Get(common.NetworkHeader), ShouldEqual, "4g")
+			So(headers.Get("Accept-Language"), ShouldEqual, "en")
+
+			c.Token = func(ctx context.Context) (string, error) { return "service-token", nil }
+			So(c.Get(ctx, "/users/1", nil), ShouldBeNil)
+			So(headers.Get("Authorization"), ShouldEqual, "Bearer service-token")
+		})
+		Convey("Test forward context from inbound request", func() {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer inbound")
+			r.Header.Set(common.OptHeader, "opt-in")
+			r.Header.Set(common.DeviceHeader, "Web")
+			So(c.Get(common.ForwardContext(r), "/users/1", nil), ShouldBeNil)
+			So(headers.Get("Authorization"), ShouldEqual, "Bearer inbound")
+			So(headers.Get(common.OptHeader), ShouldEqual, "opt-in")
+			So(headers.Get(common.DeviceHeader), ShouldEqual, "Web")
+		})
+		Convey("Test failure returns typed error", func() {
+			err := c.Get(context.Background(), "/users/2", nil)
+			So(errors.Is(err, common.NewError(errCodeUserNotFound)), ShouldBeTrue)
+			var e *common.Error
+			So(errors.As(err, &e), ShouldBeTrue)
+			So(e.Opt, ShouldEqual, "opt-b")
+			So(e.HTTPStatus, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Test problem with success code does not panic", func() {
+			var s string
+			err := c.Get(context.Background(), "/bad-problem", &s)
+			So(err, ShouldNotBeNil)
+			So(common.AsError(err).HTTPStatus, ShouldEqual, http.StatusBadGateway)
+		})
+		Convey("Test page list is decoded", func() {
+			var users []testUser
+			page, err := c.GetPage(context.Background(), "/users", &users)
+			So(err, ShouldBeNil)
+			So(users, ShouldHaveLength, 2)
+			So(page.TotalRecords, ShouldEqual, 2)
+		})
+		Convey("Test idempotent calls are retried", func() {
+			var s string
+			So(c.Get(context.Background(), "/flaky", &s), ShouldNotBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+
+			atomic.StoreInt32(&calls, 0)
+			c.MaxRetries = 3
+			So(c.Get(context.Background(), "/flaky", &s), ShouldBeNil)
+			So(s, ShouldEqual, "ok")
+			So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+
+			atomic.StoreInt32(&calls, 0)
+			err := c.Post(context.Background(), "/flaky", map[string]int{"a": 1}, nil)
+			So(common.AsError(err).HTTPStatus, ShouldEqual, http.StatusBadGateway)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,150 @@
+// jwtinspect 离线解码并校验JWT token，用于排查用户反馈的token问题。
+//
+// 用法：
+//
+//	jwtinspect [-secret 密钥 | -key 密钥文件] [-aud 收件人] [-iss 发件人] [token]
+//
+// 未给出token参数时从标准输入读取。给出-secret或-key时校验签名，-key可以是HMAC密钥或PEM格式的RSA/ECDSA公钥。
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+
+	"github.com/aluka-7/common/jwt"
+)
+
+// timeClaims 需要转换为可读时间的声明
+var timeClaims = map[string]bool{"exp": true, "iat": true, "nbf": true}
+
+func main() {
+	secret := flag.String("secret", "", "HMAC签名密钥")
+	keyFile := flag.String("key", "", "密钥文件，HMAC密钥或PEM格式的RSA/ECDSA公钥")
+	aud := flag.String("aud", "", "期望的收件人(aud)，为空时不校验")
+	iss := flag.String("iss", "", "期望的发件人(iss)，为空时不校验")
+	flag.Parse()
+
+	tokenString, err := readToken(flag.Arg(0))
+	if err != nil {
+		fatal("读取token失败: %v", err)
+	}
+	d, err := jwt.Decode(tokenString)
+	if err != nil {
+		fatal("解码token失败: %v", err)
+	}
+	printSection("Header", d.Header)
+	printSection("Claims", d.Claims)
+
+	if *secret == "" && *keyFile == "" {
+		fmt.Println("\n未提供密钥，跳过签名校验")
+		return
+	}
+	key, err := loadKey(*secret, *keyFile)
+	if err != nil {
+		fatal("加载密钥失败: %v", err)
+	}
+	if err := verify(tokenString, key, *aud, *iss); err != nil {
+		fmt.Printf("\n校验失败: %s (%v)\n", jwt.Reason(err), err)
+		os.Exit(2)
+	}
+	fmt.Println("\n校验通过")
+}
+
+func readToken(arg string) (string, error) {
+	if arg != "" {
+		return trimBearer(arg), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if line = trimBearer(line); line != "" {
+		return line, nil
+	}
+	if err == nil {
+		err = fmt.Errorf("token为空")
+	}
+	return "", err
+}
+
+// trimBearer 去掉首尾空白和Authorization头中的Bearer前缀，方便直接粘贴请求头的值
+func trimBearer(s string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "Bearer "))
+}
+
+// loadKey 加载校验密钥，密钥文件能解析为PEM公钥时返回公钥，否则将文件内容作为HMAC密钥。
+func loadKey(secret, keyFile string) (interface{}, error) {
+	if keyFile == "" {
+		return []byte(secret), nil
+	}
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, err := jwtgo.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return rsaKey, nil
+	}
+	if ecKey, err := jwtgo.ParseECPublicKeyFromPEM(data); err == nil {
+		return ecKey, nil
+	}
+	return bytes.TrimSpace(data), nil
+}
+
+func verify(tokenString string, key interface{}, aud, iss string) error {
+	claims := jwtgo.MapClaims{}
+	_, err := jwtgo.ParseWithClaims(tokenString, claims, func(token *jwtgo.Token) (interface{}, error) {
+		_, hmac := token.Method.(*jwtgo.SigningMethodHMAC)
+		_, isSecret := key.([]byte)
+		if hmac != isSecret {
+			return nil, fmt.Errorf("签名算法[%v]与密钥类型不匹配", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil {
+		return err
+	}
+	if aud != "" && !claims.VerifyAudience(aud, true) {
+		return jwtgo.NewValidationError("JWT接收者不匹配", jwtgo.ValidationErrorAudience)
+	}
+	if iss != "" && !claims.VerifyIssuer(iss, true) {
+		return jwtgo.NewValidationError("JWT签发者不匹配", jwtgo.ValidationErrorIssuer)
+	}
+	return nil
+}
+
+func printSection(title string, values map[string]interface{}) {
+	fmt.Printf("%s:\n", title)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, _ := json.Marshal(values[k])
+		fmt.Printf("  %-8s %s", k, v)
+		if n, ok := values[k].(float64); ok && timeClaims[k] {
+			fmt.Printf("  (%s)", humanTime(time.Unix(int64(n), 0)))
+		}
+		fmt.Println()
+	}
+}
+
+func humanTime(t time.Time) string {
+	d := time.Until(t).Round(time.Second)
+	if d >= 0 {
+		return fmt.Sprintf("%s, %s后", t.Format(time.RFC3339), d)
+	}
+	return fmt.Sprintf("%s, %s前", t.Format(time.RFC3339), -d)
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
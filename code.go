@@ -0,0 +1,190 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage 错误码默认消息使用的语言
+var DefaultLanguage = "zh-CN"
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[int]Code)
+)
+
+// 公共错误码，业务错误码不应与之重复
+var (
+	CodeOK              = RegisterCode(200, "成功", map[string]string{"en": "success"})
+	ErrCodeBadRequest   = RegisterCode(400, "请求参数错误", map[string]string{"en": "bad request"})
+	ErrCodeUnauthorized = RegisterCode(401, "未登录或登录已过期", map[string]string{"en": "unauthorized"})
+	ErrCodeForbidden    = RegisterCode(403, "没有访问权限", map[string]string{"en": "forbidden"})
+	ErrCodeNotFound     = RegisterCode(404, "资源不存在", map[string]string{"en": "not found"})
+	ErrCodeCanceled     = RegisterCode(499, "请求已取消", map[string]string{"en": "request canceled"})
+	ErrCodeInternal     = RegisterCode(500, "服务器内部错误", map[string]string{"en": "internal server error"})
+	ErrCodeUnavailable  = RegisterCode(503, "服务暂不可用", map[string]string{"en": "service unavailable"})
+	ErrCodeTimeout      = RegisterCode(504, "请求超时", map[string]string{"en": "request timeout"})
+)
+
+// Code 错误码，由RegisterCode统一注册，包含默认消息和各语言的翻译，消息中可以使用fmt格式化占位符。
+type Code struct {
+	code         int
+	message      string            // 默认消息，语言为DefaultLanguage
+	translations map[string]string // 语言 -> 消息
+}
+
+// RegisterCode 注册错误码，一般在包级变量中声明，同一错误码重复注册时panic。
+// @code 错误码
+// @message 默认消息
+// @translations 其他语言的消息，键为语言标签，如en、en-US、zh-TW
+func RegisterCode(code int, message string, translations map[string]string) Code {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if old, ok := registry[code]; ok {
+		panic(fmt.Sprintf("错误码[%d]重复注册: %q 与 %q", code, old.message, message))
+	}
+	c := newCode(code, message, translations)
+	registry[code] = c
+	return c
+}
+
+// newCode 创建不注册的错误码，也用于其他需要多语言的消息(如参数校验规则的消息)
+func newCode(code int, message string, translations map[string]string) Code {
+	c := Code{code: code, message: message, translations: make(map[string]string, len(translations))}
+	for lang, msg := range translations {
+		c.translations[strings.ToLower(lang)] = msg
+	}
+	return c
+}
+
+// LookupCode 根据错误码的值查找已注册的错误码
+func LookupCode(code int) (Code, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := registry[code]
+	return c, ok
+}
+
+// Code 返回错误码的值
+func (c Code) Code() int {
+	return c.code
+}
+
+// Message 按给定的语言偏好顺序返回第一个可用的消息，都不可用时返回默认消息，args不为空时用于格式化消息。
+func (c Code) Message(langs []string, args ...interface{}) string {
+	msg := c.localize(langs)
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func (c Code) localize(langs []string) string {
+	def := strings.ToLower(DefaultLanguage)
+	for _, lang := range langs {
+		lang = strings.ToLower(lang)
+		if msg, ok := c.translations[lang]; ok {
+			return msg
+		}
+		if lang == def {
+			return c.message
+		}
+		base := baseLanguage(lang)
+		if msg, ok := c.translations[base]; ok {
+			return msg
+		}
+		if base == baseLanguage(def) {
+			return c.message
+		}
+		for t, msg := range c.translations {
+			if baseLanguage(t) == base {
+				return msg
+			}
+		}
+	}
+	return c.message
+}
+
+func baseLanguage(lang string) string {
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		return lang[:i]
+	}
+	return lang
+}
+
+// OK 返回成功的状态
+func OK() Result {
+	return Result{Code: CodeOK.code, Message: CodeOK.message}
+}
+
+// Fail 使用错误码的默认消息构造失败的状态，args用于格式化消息
+func Fail(c Code, args ...interface{}) Result {
+	return Result{Code: c.code, Message: c.Message(nil, args...)}
+}
+
+// FailCtx 使用上下文中的语言偏好构造失败的状态，上下文中的语言由WithLanguages或LanguageHandler设置
+func FailCtx(ctx context.Context, c Code, args ...interface{}) Result {
+	return Result{Code: c.code, Message: c.Message(LanguagesFromContext(ctx), args...)}
+}
+
+type languagesKey struct{}
+
+// WithLanguages 将语言偏好保存到上下文中，越靠前优先级越高
+func WithLanguages(ctx context.Context, langs []string) context.Context {
+	return context.WithValue(ctx, languagesKey{}, langs)
+}
+
+// LanguagesFromContext 获取上下文中的语言偏好
+func LanguagesFromContext(ctx context.Context) []string {
+	langs, _ := ctx.Value(languagesKey{}).([]string)
+	return langs
+}
+
+// LanguageHandler 解析请求的Accept-Language头并将语言偏好保存到请求上下文中
+func LanguageHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if langs := ParseAcceptLanguage(r.Header.Get("Accept-Language")); len(langs) > 0 {
+			r = r.WithContext(WithLanguages(r.Context(), langs))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ParseAcceptLanguage 解析Accept-Language头，按权重由高到低返回语言标签，忽略*和权重为0的语言。
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var ws []weighted
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lang, q := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			lang = strings.TrimSpace(part[:i])
+			if v := strings.TrimSpace(part[i+1:]); strings.HasPrefix(v, "q=") {
+				if f, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if lang == "*" || q <= 0 {
+			continue
+		}
+		ws = append(ws, weighted{lang, q})
+	}
+	sort.SliceStable(ws, func(i, j int) bool { return ws[i].q > ws[j].q })
+	langs := make([]string, len(ws))
+	for i, w := range ws {
+		langs[i] = w.lang
+	}
+	return langs
+}
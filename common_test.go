@@ -1,10 +1,18 @@
 package common_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/aluka-7/common"
 	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestPage(t *testing.T) {
@@ -33,3 +41,155 @@ func TestPage(t *testing.T) {
 		})
 	})
 }
+
+var errCodeUserNotFound = common.RegisterCode(10001, "用户[%s]不存在", map[string]string{"en": "user [%s] not found", "zh-TW": "用戶[%s]不存在"})
+
+func TestCode(t *testing.T) {
+	Convey("Test Code Registry", t, func() {
+		code := errCodeUserNotFound
+		Convey("Test default message", func() {
+			r := common.Fail(code, "tom")
+			So(r.Code, ShouldEqual, 10001)
+			So(r.Message, ShouldEqual, "用户[tom]不存在")
+		})
+		Convey("Test message from accept language", func() {
+			langs := common.ParseAcceptLanguage("fr;q=0.9, en-US;q=0.8, *;q=0.5")
+			So(langs, ShouldResemble, []string{"fr", "en-US"})
+			r := common.FailCtx(common.WithLanguages(context.Background(), langs), code, "tom")
+			So(r.Message, ShouldEqual, "user [tom] not found")
+			r = common.FailCtx(common.WithLanguages(context.Background(), []string{"zh-TW"}), code, "tom")
+			So(r.Message, ShouldEqual, "用戶[tom]不存在")
+		})
+		Convey("Test duplicate code panics", func() {
+			So(func() { common.RegisterCode(10001, "重复", nil) }, ShouldPanic)
+		})
+	})
+}
+
+func TestError(t *testing.T) {
+	Convey("Test Error", t, func() {
+		Convey("Test errors.Is and As through wrapping", func() {
+			cause := errors.New("record not found")
+			err := fmt.Errorf("query user: %w", common.WrapError(cause, errCodeUserNotFound, "tom"))
+			So(errors.Is(err, common.NewError(errCodeUserNotFound)), ShouldBeTrue)
+			So(errors.Is(err, cause), ShouldBeTrue)
+			var e *common.Error
+			So(errors.As(err, &e), ShouldBeTrue)
+			So(e.Message, ShouldEqual, "用户[tom]不存在")
+			So(e.Status().HTTP, ShouldEqual, http.StatusBadRequest)
+			So(status.Code(e), ShouldEqual, codes.FailedPrecondition)
+		})
+		Convey("Test result from error", func() {
+			So(common.ResultFromError(nil).Code, ShouldEqual, common.CodeOK.Code())
+			So(common.ResultFromError(context.Canceled).Code, ShouldEqual, common.ErrCodeCanceled.Code())
+			ctx, cancel := context.WithTimeout(context.Background(), 0)
+			defer cancel()
+			<-ctx.Done()
+			So(common.ResultFromError(ctx.Err()).Code, ShouldEqual, common.ErrCodeTimeout.Code())
+			r := common.ResultFromError(errors.New("dial tcp: connection refused"))
+			So(r.Code, ShouldEqual, common.ErrCodeInternal.Code())
+			So(r.Message, ShouldNotContainSubstring, "dial")
+		})
+		Convey("Test error from result", func() {
+			So(common.ErrorFromResult(common.OK()), ShouldBeNil)
+			err := common.ErrorFromResult(common.Fail(errCodeUserNotFound, "tom"))
+			So(errors.Is(err, common.NewError(errCodeUserNotFound)), ShouldBeTrue)
+		})
+	})
+}
+
+func TestGrpcStatus(t *testing.T) {
+	Convey("Test gRPC Status", t, func() {
+		Convey("Test result round trip through status", func() {
+			r := common.Fail(errCodeUserNotFound, "tom")
+			r.SetOpt("op-1")
+			s := common.StatusFromResult(r)
+			So(s.Code(), ShouldEqual, codes.FailedPrecondition)
+			So(common.ResultFromStatus(s), ShouldResemble, r)
+		})
+		Convey("Test status without detail", func() {
+			r := common.ResultFromStatus(status.New(codes.NotFound, "no such user"))
+			So(r.Code, ShouldEqual, common.ErrCodeNotFound.Code())
+			So(r.Message, ShouldEqual, "no such user")
+		})
+		Convey("Test interceptors", func() {
+			server := common.UnaryServerInterceptor()
+			_, err := server(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/user.User/Get"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, common.NewError(errCodeUserNotFound, "tom")
+			})
+			So(status.Code(err), ShouldEqual, codes.FailedPrecondition)
+
+			client := common.UnaryClientInterceptor()
+			err = client(context.Background(), "/user.User/Get", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return err
+			})
+			var e *common.Error
+			So(errors.As(err, &e), ShouldBeTrue)
+			So(e.Code, ShouldEqual, 10001)
+			So(e.Message, ShouldEqual, "用户[tom]不存在")
+		})
+	})
+}
+
+type testUser struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedResult(t *testing.T) {
+	Convey("Test Typed Result", t, func() {
+		users := []testUser{{1, "Aluka-7"}, {2, "Brandon"}}
+		Convey("Test typed dto marshals like DtoResult", func() {
+			typed := common.DtoResultOf[testUser]{Result: common.OK(), Data: users[0]}
+			untyped := common.DtoResult{Result: common.OK(), Data: users[0]}
+			So(common.Json(typed, false), ShouldEqual, common.Json(untyped, false))
+
+			dto, err := common.DecodeDto[testUser](strings.NewReader(common.Json(untyped, false)))
+			So(err, ShouldBeNil)
+			So(dto.Data, ShouldResemble, users[0])
+		})
+		Convey("Test typed page marshals like PageResult", func() {
+			typed := common.PageResultOf[[]testUser]{Result: common.OK(), Pagination: common.Page{PageSize: 20, TotalPages: 1, TotalRecords: 2}, List: users}
+			untyped := common.PageResult{Result: typed.Result, Pagination: typed.Pagination, List: users}
+			So(common.Json(typed, false), ShouldEqual, common.Json(untyped, false))
+
+			page, err := common.DecodePage[[]testUser](strings.NewReader(common.Json(untyped, false)))
+			So(err, ShouldBeNil)
+			So(page.List, ShouldResemble, users)
+			So(page.Pagination.TotalRecords, ShouldEqual, 2)
+		})
+	})
+}
+
+func TestResultPb(t *testing.T) {
+	Convey("Test Result Protobuf Converters", t, func() {
+		Convey("Test result", func() {
+			r := common.Fail(errCodeUserNotFound, "tom")
+			r.SetOpt("op-1")
+			var back common.Result
+			So(*back.ForPb(r.ToPb()), ShouldResemble, r)
+		})
+		Convey("Test dto keeps json unchanged", func() {
+			dto := common.DtoResult{Result: common.OK(), Data: map[string]interface{}{"id": 9007199254740993, "name": "Aluka-7"}}
+			p, err := dto.ToPb()
+			So(err, ShouldBeNil)
+			var back common.DtoResult
+			So(back.ForPb(p), ShouldBeNil)
+			So(common.Json(back, false), ShouldEqual, common.Json(dto, false))
+		})
+		Convey("Test page", func() {
+			page := common.PageResultOf[[]testUser]{Result: common.OK(), Pagination: common.Page{PageSize: 20, TotalPages: 1, TotalRecords: 1}, List: []testUser{{1, "Aluka-7"}}}
+			page.Opt = "op-1"
+			p, err := page.ToPb()
+			So(err, ShouldBeNil)
+			var back common.PageResultOf[[]testUser]
+			So(back.ForPb(p), ShouldBeNil)
+			So(back, ShouldResemble, page)
+		})
+		Convey("Test callback", func() {
+			cb := common.NewCallBack(1, testUser{1, "Aluka-7"})
+			var back common.Callback
+			So(*back.ForPb(cb.ToPb()), ShouldResemble, cb)
+		})
+	})
+}
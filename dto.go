@@ -2,6 +2,9 @@ package common
 
 import (
 	"encoding/json"
+	"io"
+
+	"github.com/aluka-7/common/pb"
 )
 
 // NoProtectedURIProvider 当前运营子系统中对不需要进行安全拦截的地址提供器，各运营子系统只需要实现该接口并注册到中即可。
@@ -33,42 +36,169 @@ func (r *Result) SetOpt(opt string) {
 	r.Opt = opt
 }
 
-//DtoResult 返回带有对象信息
-type DtoResult struct {
+func (r Result) ToPb() *pb.Result {
+	return &pb.Result{Code: int32(r.Code), Msg: r.Message, Opt: r.Opt}
+}
+func (r *Result) ForPb(pr *pb.Result) *Result {
+	r.Code = int(pr.Code)
+	r.Message = pr.Msg
+	r.Opt = pr.Opt
+	return r
+}
+
+// DtoResultOf 返回带有指定类型的对象信息，与DtoResult序列化后的JSON完全一致，客户端可以直接解码为具体类型
+type DtoResultOf[T any] struct {
 	Result
-	Data interface{} `json:"data"` // 具体返回数据的JSON格式
+	Data T `json:"data"` // 具体返回数据的JSON格式
 }
+
+//DtoResult 返回带有对象信息
+type DtoResult = DtoResultOf[any]
+
+// ToPb 转换为protobuf消息，Data序列化为JSON保存，转换不会进行脱敏
+func (d DtoResultOf[T]) ToPb() (*pb.DtoResult, error) {
+	data, err := Codec().Marshal(d.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DtoResult{Code: int32(d.Code), Msg: d.Message, Opt: d.Opt, Data: data}, nil
+}
+
+// ForPb 从protobuf消息还原，T为any时Data还原为json.RawMessage以保持原始JSON不变
+func (d *DtoResultOf[T]) ForPb(pd *pb.DtoResult) error {
+	d.Code, d.Message, d.Opt = int(pd.Code), pd.Msg, pd.Opt
+	return unmarshalData(pd.Data, &d.Data)
+}
+// Page 分页信息，由NewPage根据Pagination生成
 type Page struct {
-	PageSize     int `json:"pageSize"`     // 每页大小
-	TotalPages   int `json:"totalPages"`   // 总页数
-	TotalRecords int `json:"totalRecords"` // 总记录数
+	PageNo       int    `json:"page"`             // 当前页码，从1开始
+	PageSize     int    `json:"pageSize"`         // 每页大小
+	TotalPages   int    `json:"totalPages"`       // 总页数
+	TotalRecords int    `json:"totalRecords"`     // 总记录数
+	HasNext      bool   `json:"hasNext"`          // 是否存在下一页
+	HasPrevious  bool   `json:"hasPrevious"`      // 是否存在上一页
+	Cursor       string `json:"cursor,omitempty"` // 游标分页时获取下一页的游标
 }
 
-// PageResult 返回带在分页信息
-type PageResult struct {
+// NewPage 根据分页器生成分页信息，p为nil时使用DefaultPagination()
+func NewPage(p *Pagination) Page {
+	if p == nil {
+		p = DefaultPagination()
+	}
+	return Page{
+		PageNo:       p.PageNo,
+		PageSize:     p.PageSize,
+		TotalPages:   p.TotalPage(),
+		TotalRecords: p.TotalRecords,
+		HasNext:      p.HasNext(),
+		HasPrevious:  p.HasPrevious(),
+	}
+}
+
+func (p Page) ToPb() pb.Page {
+	return pb.Page{
+		PageNo:       int32(p.PageNo),
+		PageSize:     int32(p.PageSize),
+		TotalPages:   int32(p.TotalPages),
+		TotalRecords: int32(p.TotalRecords),
+		HasNext:      p.HasNext,
+		HasPrevious:  p.HasPrevious,
+		Cursor:       p.Cursor,
+	}
+}
+func (p *Page) ForPb(pp pb.Page) *Page {
+	p.PageNo = int(pp.PageNo)
+	p.PageSize = int(pp.PageSize)
+	p.TotalPages = int(pp.TotalPages)
+	p.TotalRecords = int(pp.TotalRecords)
+	p.HasNext = pp.HasNext
+	p.HasPrevious = pp.HasPrevious
+	p.Cursor = pp.Cursor
+	return p
+}
+
+// PageResultOf 返回带有分页信息的指定类型记录集，T为记录集的类型(如[]User)，与PageResult序列化后的JSON完全一致
+type PageResultOf[T any] struct {
 	Result
-	Pagination Page        `json:"pagination"`
-	Opt        string      `json:"opt"`  // 操作编号
-	List       interface{} `json:"data"` // 记录集
+	Pagination Page `json:"pagination"`
+	List       T    `json:"data"` // 记录集
+}
+
+// PageResult 返回带在分页信息
+type PageResult = PageResultOf[any]
+
+// NewPageResult 使用分页器和记录集构造成功的分页结果
+func NewPageResult[T any](p *Pagination, list T) PageResultOf[T] {
+	return PageResultOf[T]{Result: OK(), Pagination: NewPage(p), List: list}
+}
+
+// ToPb 转换为protobuf消息，List序列化为JSON保存，转换不会进行脱敏
+func (p PageResultOf[T]) ToPb() (*pb.PageResult, error) {
+	data, err := Codec().Marshal(p.List)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PageResult{Code: int32(p.Code), Msg: p.Message, Opt: p.Opt, Pagination: p.Pagination.ToPb(), Data: data}, nil
+}
+
+// ForPb 从protobuf消息还原，T为any时List还原为json.RawMessage以保持原始JSON不变
+func (p *PageResultOf[T]) ForPb(pp *pb.PageResult) error {
+	p.Code, p.Message, p.Opt = int(pp.Code), pp.Msg, pp.Opt
+	p.Pagination.ForPb(pp.Pagination)
+	return unmarshalData(pp.Data, &p.List)
+}
+
+// unmarshalData 还原JSON数据，目标为interface{}时直接保存原始JSON
+func unmarshalData[T any](data []byte, v *T) error {
+	if raw, ok := any(v).(*any); ok {
+		if len(data) > 0 {
+			*raw = json.RawMessage(data)
+		}
+		return nil
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return Codec().Unmarshal(data, v)
+}
+
+// DecodeDto 将响应内容解码为指定数据类型的DtoResultOf
+func DecodeDto[T any](r io.Reader) (dto DtoResultOf[T], err error) {
+	err = decodeJSON(r, &dto)
+	return
+}
+
+// DecodePage 将响应内容解码为指定记录集类型的PageResultOf
+func DecodePage[T any](r io.Reader) (page PageResultOf[T], err error) {
+	err = decodeJSON(r, &page)
+	return
 }
 
 type Callback struct {
-	CbType int    `json:"cbType"` // 业务数据JSON里的参数，回调类型代码
-	Opt    string `json:"opt"`    // 操作编号
-	Data   string `json:"data"`   // 具体返回数据的JSON格式
+	CbType  int    `json:"cbType"`            // 业务数据JSON里的参数，回调类型代码
+	Version int    `json:"version,omitempty"` // 数据结构版本，为0时视为1
+	Opt     string `json:"opt"`               // 操作编号
+	Data    string `json:"data"`              // 具体返回数据的JSON格式
 }
 
 func NewCallBack(cbType int, v interface{}) Callback {
-	data := Json(v, false)
+	data := Json(Trusted(v), false) // 回调数据用于系统间交互，不进行脱敏
 	return Callback{CbType: cbType, Data: data}
 }
 
-func Json(v interface{}, indent bool) string {
-	var n []byte
-	if indent {
-		n, _ = json.MarshalIndent(v, "", "\t")
-	} else {
-		n, _ = json.Marshal(v)
-	}
-	return string(n)
+// NewVersionedCallBack 创建指定数据结构版本的回调
+func NewVersionedCallBack(cbType, version int, v interface{}) Callback {
+	cb := NewCallBack(cbType, v)
+	cb.Version = version
+	return cb
+}
+func (c Callback) ToPb() *pb.Callback {
+	return &pb.Callback{CbType: int32(c.CbType), Opt: c.Opt, Data: c.Data, Version: int32(c.Version)}
+}
+func (c *Callback) ForPb(pc *pb.Callback) *Callback {
+	c.CbType = int(pc.CbType)
+	c.Version = int(pc.Version)
+	c.Opt = pc.Opt
+	c.Data = pc.Data
+	return c
 }
@@ -0,0 +1,56 @@
+package common_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var update = flag.Bool("update", false, "更新testdata中的golden文件")
+
+// assertGolden 比较序列化结果与golden文件，使用go test -update重新生成
+func assertGolden(name string, v interface{}) {
+	path := filepath.Join("testdata", name+".golden")
+	got := common.Json(v, true) + "\n"
+	if *update {
+		So(os.WriteFile(path, []byte(got), 0644), ShouldBeNil)
+	}
+	want, err := os.ReadFile(path)
+	So(err, ShouldBeNil)
+	So(got, ShouldEqual, string(want))
+}
+
+func TestEnvelopeGolden(t *testing.T) {
+	Convey("Test Envelope Wire Format", t, func() {
+		users := []testUser{{1, "Aluka-7"}, {2, "Brandon"}}
+		Convey("Test page result", func() {
+			p := common.NewPagination(2, 2, 0)
+			p.SetTotalRecord(5)
+			page := common.NewPageResult(p, users)
+			page.SetOpt("01GBQ8Z4ZJ6X7V0Q0K9E5Y2M3N")
+			assertGolden("page_result", page)
+		})
+		Convey("Test empty page result", func() {
+			p := common.DefaultPagination()
+			p.SetTotalRecord(0)
+			assertGolden("page_result_empty", common.NewPageResult(p, []testUser{}))
+		})
+		Convey("Test cursor page result", func() {
+			// 每页2条，当前页的最后一条记录id为2，游标{"id":2}指向下一页
+			p := common.NewPagination(1, len(users), 0)
+			p.SetTotalRecord(5)
+			page := common.NewPageResult(p, users)
+			page.Pagination.Cursor = "eyJpZCI6Mn0"
+			assertGolden("page_result_cursor", page)
+		})
+		Convey("Test dto result", func() {
+			dto := common.DtoResult{Result: common.OK(), Data: users[0]}
+			dto.SetOpt("01GBQ8Z4ZJ6X7V0Q0K9E5Y2M3N")
+			assertGolden("dto_result", dto)
+		})
+	})
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error 携带错误码的错误，可以包装底层错误并支持errors.Is/As，用于在error和Result之间转换。
+type Error struct {
+	Code       int        // 错误码，同Result.Code
+	Message    string     // 错误信息，同Result.Message
+	HTTPStatus int        // 显式指定的HTTP状态码，为0时写入时按当前策略由错误码推导，见Status
+	GRPCCode   codes.Code // 显式指定的gRPC状态码，为codes.OK时写入时按当前策略由错误码推导，见Status
+	Opt        string     // 操作编号
+	cause      error      // 被包装的底层错误
+}
+
+// NewError 使用错误码的默认消息创建错误，HTTP和gRPC状态码在写入时由错误码推导
+func NewError(c Code, args ...interface{}) *Error {
+	return newError(c.code, c.Message(nil, args...), nil)
+}
+
+// WrapError 使用错误码包装底层错误，底层错误只用于日志和errors.Is/As，不会出现在返回给调用方的消息中
+func WrapError(cause error, c Code, args ...interface{}) *Error {
+	return newError(c.code, c.Message(nil, args...), cause)
+}
+
+func newError(code int, message string, cause error) *Error {
+	return &Error{Code: code, Message: message, cause: cause}
+}
+
+// Status 返回错误对应的传输层状态，按当前的StatusPolicy推导，HTTPStatus或GRPCCode显式指定时使用指定的值。
+// 状态在调用时推导，包级变量声明的错误在SetStatusPolicy之后同样使用新的策略。
+func (e *Error) Status() Status {
+	s := CurrentStatusPolicy().Status(e.Code)
+	if e.HTTPStatus != 0 {
+		s.HTTP = e.HTTPStatus
+	}
+	if e.GRPCCode != codes.OK {
+		s.GRPC = e.GRPCCode
+	}
+	return s
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("[%d]%s: %v", e.Code, e.Message, e.cause)
+	}
+	return fmt.Sprintf("[%d]%s", e.Code, e.Message)
+}
+
+// Unwrap 返回被包装的底层错误
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is 错误码相同即视为同一错误，可以使用NewError声明的错误作为errors.Is的目标
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// GRPCStatus 转换为gRPC状态，错误码、消息和操作编号保存在状态详情中，status.FromError和status.Code会使用该方法
+func (e *Error) GRPCStatus() *status.Status {
+	return withResultDetail(status.New(e.Status().GRPC, e.Message), e.Result())
+}
+
+// Result 转换为Result
+func (e *Error) Result() Result {
+	return Result{Code: e.Code, Message: e.Message, Opt: e.Opt}
+}
+
+// ResultFromError 将任意错误转换为Result，nil转换为成功状态，上下文取消和超时分别转换为ErrCodeCanceled和ErrCodeTimeout，
+// gRPC状态错误按ResultFromStatus转换，未知错误转换为ErrCodeInternal且不暴露错误详情。
+func ResultFromError(err error) Result {
+	return AsError(err).Result()
+}
+
+// AsError 将任意错误转换为*Error，规则同ResultFromError，err为nil时返回成功状态的*Error
+func AsError(err error) *Error {
+	var e *Error
+	switch {
+	case err == nil:
+		return newError(CodeOK.code, CodeOK.message, nil)
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, context.Canceled):
+		return WrapError(err, ErrCodeCanceled)
+	case errors.Is(err, context.DeadlineExceeded):
+		return WrapError(err, ErrCodeTimeout)
+	}
+	if s, ok := status.FromError(err); ok {
+		return errorFromStatus(s, err)
+	}
+	return WrapError(err, ErrCodeInternal)
+}
+
+// ErrorFromResult 将调用其他服务得到的Result转换为错误，成功状态返回nil
+func ErrorFromResult(r Result) error {
+	if r.Code == CodeOK.code {
+		return nil
+	}
+	e := newError(r.Code, r.Message, nil)
+	e.Opt = r.Opt
+	return e
+}
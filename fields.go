@@ -0,0 +1,299 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldsParam 指定返回字段的请求参数名
+const FieldsParam = "fields"
+
+// ErrUnknownField 投影字段在返回数据中不存在
+var ErrUnknownField = errors.New("未知的返回字段")
+
+// Fields 返回字段投影，键为JSON字段名，值为下级字段的投影，值为nil表示返回该字段的全部内容。
+// 数组和切片的投影作用于其中的每个元素。
+type Fields map[string]Fields
+
+// ParseFields 解析逗号分隔的字段列表，下级字段使用.分隔，如"id,name,items.sku,items.price"，
+// 同时给出上级和下级字段时返回上级字段的全部内容，s为空时返回nil表示不投影。
+func ParseFields(s string) (Fields, error) {
+	var f Fields
+	for _, path := range strings.Split(s, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		if f == nil {
+			f = make(Fields)
+		}
+		cur := f
+		names := strings.Split(path, ".")
+		for i, name := range names {
+			if name = strings.TrimSpace(name); name == "" {
+				return nil, WrapError(fmt.Errorf("字段[%s]格式错误", path), ErrCodeBadRequest)
+			}
+			sub, ok := cur[name]
+			if ok && sub == nil {
+				break // 已经返回全部内容
+			}
+			if i == len(names)-1 {
+				cur[name] = nil
+				break
+			}
+			if sub == nil {
+				sub = make(Fields)
+				cur[name] = sub
+			}
+			cur = sub
+		}
+	}
+	return f, nil
+}
+
+// String 转换为ParseFields可以解析的字段列表，字段按名称排序
+func (f Fields) String() string {
+	var paths []string
+	f.collect("", &paths)
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+func (f Fields) collect(prefix string, paths *[]string) {
+	for name, sub := range f {
+		if sub == nil {
+			*paths = append(*paths, prefix+name)
+		} else {
+			sub.collect(prefix+name+".", paths)
+		}
+	}
+}
+
+type fieldsKey struct{}
+
+// WithFields 将返回字段投影保存到上下文中，写入DtoResult和PageResult时会对Data和List进行投影
+func WithFields(ctx context.Context, f Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// FieldsFromContext 获取上下文中的返回字段投影
+func FieldsFromContext(ctx context.Context) Fields {
+	f, _ := ctx.Value(fieldsKey{}).(Fields)
+	return f
+}
+
+// FieldsFromRequest 获取请求的返回字段投影，优先使用上下文中的投影(如通过Query.Fields设置)，否则解析fields查询参数
+func FieldsFromRequest(r *http.Request) (Fields, error) {
+	if f := FieldsFromContext(r.Context()); f != nil {
+		return f, nil
+	}
+	return ParseFields(r.URL.Query().Get(FieldsParam))
+}
+
+// Project 按投影返回v的部分字段，结果为map、切片等通用JSON结构，f为nil时原样返回v。
+// 投影先根据v的类型校验字段，字段不存在时返回以ErrCodeBadRequest包装的ErrUnknownField。
+// 投影结果不再保留mask标签，因此会在投影前进行脱敏。
+func Project(v interface{}, f Fields) (interface{}, error) {
+	return project(v, f, true)
+}
+
+func project(v interface{}, f Fields, mask bool) (interface{}, error) {
+	if f == nil || v == nil {
+		return v, nil
+	}
+	if err := checkFields(reflect.ValueOf(v), f, ""); err != nil {
+		return nil, err
+	}
+	if mask {
+		v = Mask(v)
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, v, false); err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	dec.UseNumber()
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return prune(generic, f), nil
+}
+
+// Project 对Data进行投影，规则同Project函数
+func (d DtoResultOf[T]) Project(f Fields) (DtoResult, error) {
+	return d.project(f, true)
+}
+
+func (d DtoResultOf[T]) project(f Fields, mask bool) (DtoResult, error) {
+	data, err := project(d.Data, f, mask)
+	return DtoResult{Result: d.Result, Data: data}, err
+}
+
+// Project 对List进行投影，规则同Project函数
+func (p PageResultOf[T]) Project(f Fields) (PageResult, error) {
+	return p.project(f, true)
+}
+
+func (p PageResultOf[T]) project(f Fields, mask bool) (PageResult, error) {
+	list, err := project(p.List, f, mask)
+	return PageResult{Result: p.Result, Pagination: p.Pagination, List: list}, err
+}
+
+func unknownField(path string) error {
+	e := WrapError(fmt.Errorf("%w[%s]", ErrUnknownField, path), ErrCodeBadRequest)
+	e.Message = fmt.Sprintf("%s[%s=%s]", e.Message, FieldsParam, path)
+	return e
+}
+
+// checkFields 根据v的类型校验投影字段，interface类型按实际的值校验，值为nil时无法确定类型，不做校验
+func checkFields(v reflect.Value, f Fields, prefix string) error {
+	return checkType(v.Type(), v, f, prefix)
+}
+
+func checkType(t reflect.Type, v reflect.Value, f Fields, prefix string) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		if v.IsValid() && !v.IsNil() {
+			v = v.Elem()
+			t = v.Type()
+		} else if t.Kind() == reflect.Ptr {
+			t, v = t.Elem(), reflect.Value{}
+		} else {
+			return nil
+		}
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return nil // 自定义序列化的类型无法根据类型校验
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		if t.Elem().Kind() == reflect.Interface && v.IsValid() {
+			for i := 0; i < v.Len(); i++ {
+				if err := checkType(t.Elem(), v.Index(i), f, prefix); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return checkType(t.Elem(), reflect.Value{}, f, prefix)
+	case reflect.Map:
+		for name, sub := range f {
+			if sub == nil {
+				continue
+			}
+			var ev reflect.Value
+			if v.IsValid() && t.Key().Kind() == reflect.String {
+				ev = v.MapIndex(reflect.ValueOf(name).Convert(t.Key()))
+			}
+			if err := checkType(t.Elem(), ev, sub, prefix+name+"."); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		for name, sub := range f {
+			idx, ok := jsonFieldIndex(t, name)
+			if !ok {
+				return unknownField(prefix + name)
+			}
+			if sub == nil {
+				continue
+			}
+			var fv reflect.Value
+			if v.IsValid() {
+				fv = fieldByIndex(v, idx)
+			}
+			if err := checkType(t.FieldByIndex(idx).Type, fv, sub, prefix+name+"."); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for name := range f {
+		return unknownField(prefix + name)
+	}
+	return nil
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// jsonFieldIndex 按JSON字段名查找结构体字段，规则与encoding/json一致，包括提升的匿名结构体字段
+func jsonFieldIndex(t reflect.Type, name string) ([]int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName := strings.Split(tag, ",")[0]
+		if f.Anonymous && tagName == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if idx, ok := jsonFieldIndex(ft, name); ok {
+					return append([]int{i}, idx...), true
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if tagName == "" {
+			tagName = f.Name
+		}
+		if tagName == name {
+			return []int{i}, true
+		}
+	}
+	return nil, false
+}
+
+// fieldByIndex 与reflect.Value.FieldByIndex相同，途经nil指针时返回无效值而不是panic
+func fieldByIndex(v reflect.Value, idx []int) reflect.Value {
+	for i, x := range idx {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// prune 按投影裁剪通用JSON结构
+func prune(v interface{}, f Fields) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(f))
+		for name, sub := range f {
+			if fv, ok := t[name]; ok {
+				if sub != nil {
+					fv = prune(fv, sub)
+				}
+				out[name] = fv
+			}
+		}
+		return out
+	case []interface{}:
+		for i, e := range t {
+			t[i] = prune(e, f)
+		}
+		return t
+	}
+	return v
+}
@@ -0,0 +1,103 @@
+package common_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type orderItem struct {
+	Sku   string `json:"sku"`
+	Price int    `json:"price"`
+	Note  string `json:"note,omitempty"`
+}
+
+type order struct {
+	Id      int64             `json:"id"`
+	Buyer   *maskedContact    `json:"buyer"`
+	Items   []orderItem       `json:"items"`
+	Extra   map[string]string `json:"extra"`
+	private string
+}
+
+func TestFields(t *testing.T) {
+	Convey("Test Fields", t, func() {
+		orders := []order{{
+			Id:    1,
+			Buyer: &maskedContact{Mobile: "13600009714", Email: "a@b.c"},
+			Items: []orderItem{{Sku: "A", Price: 100}, {Sku: "B", Price: 200}},
+			Extra: map[string]string{"channel": "app", "coupon": "x"},
+		}}
+
+		Convey("Test parse", func() {
+			f, err := common.ParseFields(" id, items.sku ,items, buyer.mobile")
+			So(err, ShouldBeNil)
+			So(f.String(), ShouldEqual, "buyer.mobile,id,items")
+			_, err = common.ParseFields("id,items..sku")
+			So(errors.Is(err, common.NewError(common.ErrCodeBadRequest)), ShouldBeTrue)
+			f, err = common.ParseFields("")
+			So(err, ShouldBeNil)
+			So(f, ShouldBeNil)
+		})
+		Convey("Test nested paths and arrays", func() {
+			f, _ := common.ParseFields("id,items.sku,buyer.mobile,extra.channel")
+			v, err := common.Project(orders, f)
+			So(err, ShouldBeNil)
+			So(common.Json(v, false), ShouldEqual,
+				`[{"buyer":{"mobile":"136****9714"},"extra":{"channel":"app"},"id":1,"items":[{"sku":"A"},{"sku":"B"}]}]`)
+		})
+		Convey("Test omitted fields are still known", func() {
+			f, _ := common.ParseFields("items.note")
+			_, err := common.Project(orders, f)
+			So(err, ShouldBeNil)
+		})
+		Convey("Test unknown fields are rejected", func() {
+			for _, fields := range []string{"name", "items.color", "id.value", "private", "buyer.mobile.area"} {
+				f, _ := common.ParseFields(fields)
+				_, err := common.Project(orders, f)
+				So(errors.Is(err, common.ErrUnknownField), ShouldBeTrue)
+				So(common.ResultFromError(err).Code, ShouldEqual, common.ErrCodeBadRequest.Code())
+			}
+		})
+		Convey("Test interface data is checked by value", func() {
+			f, _ := common.ParseFields("items.color")
+			_, err := common.DtoResult{Result: common.OK(), Data: orders[0]}.Project(f)
+			So(errors.Is(err, common.ErrUnknownField), ShouldBeTrue)
+		})
+		Convey("Test write page with fields parameter", func() {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/orders?fields=id,items.price", nil)
+			So(common.WritePage(rec, req, orders, common.NewPagination(1, 10, 1)), ShouldBeNil)
+			var resp common.PageResultOf[[]map[string]interface{}]
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			So(resp.List, ShouldHaveLength, 1)
+			So(resp.List[0], ShouldHaveLength, 2)
+			So(resp.Pagination.PageNo, ShouldEqual, 1)
+
+			rec = httptest.NewRecorder()
+			req = httptest.NewRequest(http.MethodGet, "/orders?fields=id,color", nil)
+			So(common.WritePage(rec, req, orders, common.NewPagination(1, 10, 1)), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+			So(rec.Body.String(), ShouldContainSubstring, "fields=color")
+		})
+		Convey("Test fields travel inside query", func() {
+			var q common.Query
+			q.SetFields("id", "buyer.email")
+			var back common.Query
+			back.ForPb(q.ToPb())
+			f, err := back.Projection()
+			So(err, ShouldBeNil)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+			req = req.WithContext(common.WithFields(req.Context(), f))
+			So(common.WriteOK(rec, req, orders[0]), ShouldBeNil)
+			So(rec.Body.String(), ShouldContainSubstring, `"data":{"buyer":{"email":"a***@b.c"},"id":1}`)
+		})
+	})
+}
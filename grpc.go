@@ -0,0 +1,151 @@
+package common
+
+import (
+	"context"
+	"io"
+
+	"github.com/aluka-7/common/pb"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StatusFromResult 将Result转换为gRPC状态，状态码由Result.Code推导，Code、Message和Opt保存在状态详情中
+func StatusFromResult(r Result) *status.Status {
+	_, c := statusOf(r.Code)
+	return withResultDetail(status.New(c, r.Message), r)
+}
+
+// ResultFromStatus 将gRPC状态转换为Result，优先使用状态详情中的Result信息，
+// 没有详情时(如调用第三方gRPC服务)根据gRPC状态码推导错误码。
+func ResultFromStatus(s *status.Status) Result {
+	for _, d := range s.Details() {
+		if rd, ok := d.(*pb.ResultDetail); ok {
+			return Result{Code: int(rd.Code), Message: rd.Msg, Opt: rd.Opt}
+		}
+	}
+	var c Code
+	switch s.Code() {
+	case codes.OK:
+		c = CodeOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		c = ErrCodeBadRequest
+	case codes.Unauthenticated:
+		c = ErrCodeUnauthorized
+	case codes.PermissionDenied:
+		c = ErrCodeForbidden
+	case codes.NotFound:
+		c = ErrCodeNotFound
+	case codes.Canceled:
+		c = ErrCodeCanceled
+	case codes.Unavailable:
+		c = ErrCodeUnavailable
+	case codes.DeadlineExceeded:
+		c = ErrCodeTimeout
+	default:
+		c = ErrCodeInternal
+	}
+	msg := s.Message()
+	if msg == "" {
+		msg = c.message
+	}
+	return Result{Code: c.code, Message: msg}
+}
+
+func withResultDetail(s *status.Status, r Result) *status.Status {
+	if s.Code() == codes.OK {
+		return s
+	}
+	ds, err := s.WithDetails(&pb.ResultDetail{Code: int32(r.Code), Msg: r.Message, Opt: r.Opt})
+	if err != nil {
+		return s
+	}
+	return ds
+}
+
+func errorFromStatus(s *status.Status, cause error) *Error {
+	r := ResultFromStatus(s)
+	e := newError(r.Code, r.Message, cause)
+	e.GRPCCode = s.Code()
+	e.Opt = r.Opt
+	return e
+}
+
+// toStatusError 将服务端返回的错误转换为携带Result详情的gRPC状态错误，未知错误记录日志后按内部错误返回，
+// 错误没有操作编号时使用上下文中的操作编号。
+func toStatusError(ctx context.Context, method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := *AsError(err) // 复制一份，避免修改包级声明的错误
+	if e.Opt == "" {
+		e.Opt = OptFromContext(ctx)
+	}
+	if e.Code == ErrCodeInternal.code {
+		log.Err(err).Ctx(ctx).Msgf("gRPC调用[%s]发生错误", method)
+	}
+	return e.GRPCStatus().Err()
+}
+
+// fromStatusError 将客户端收到的gRPC状态错误还原为*Error，io.EOF等非状态错误原样返回
+func fromStatusError(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return errorFromStatus(s, err)
+}
+
+// UnaryServerInterceptor 服务端一元拦截器，将处理函数返回的错误转换为携带Result详情的gRPC状态，
+// 需要自动填充操作编号时应放在OptUnaryServerInterceptor之后。
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, toStatusError(ctx, info.FullMethod, err)
+	}
+}
+
+// StreamServerInterceptor 服务端流拦截器，将处理函数返回的错误转换为携带Result详情的gRPC状态
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return toStatusError(ss.Context(), info.FullMethod, handler(srv, ss))
+	}
+}
+
+// UnaryClientInterceptor 客户端一元拦截器，将收到的gRPC状态错误还原为*Error
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return fromStatusError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor 客户端流拦截器，将建立流和收发消息时收到的gRPC状态错误还原为*Error
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, fromStatusError(err)
+		}
+		return &clientStream{cs}, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	return fromStatusError(s.ClientStream.SendMsg(m))
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	return fromStatusError(s.ClientStream.RecvMsg(m))
+}
+
+func (s *clientStream) CloseSend() error {
+	return fromStatusError(s.ClientStream.CloseSend())
+}
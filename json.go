@@ -0,0 +1,137 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+)
+
+// JSONCodec JSON编解码器，默认使用标准库encoding/json，可以通过SetJSONCodec替换为性能更高的实现
+// (如jsoniter、sonic等，它们都提供兼容标准库的API，只需简单适配)。
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	MarshalIndent(v interface{}, prefix, indent string) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// NewEncoder 创建写入w的编码器，编码器在每个值后写入换行符，与json.Encoder一致
+	NewEncoder(w io.Writer) JSONEncoder
+}
+
+// JSONEncoder 流式JSON编码器
+type JSONEncoder interface {
+	Encode(v interface{}) error
+	SetIndent(prefix, indent string)
+}
+
+// StdJSONCodec 基于标准库encoding/json的编解码器
+type StdJSONCodec struct{}
+
+func (StdJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (StdJSONCodec) MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(v, prefix, indent)
+}
+
+func (StdJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (StdJSONCodec) NewEncoder(w io.Writer) JSONEncoder {
+	return json.NewEncoder(w)
+}
+
+var jsonCodec atomic.Value
+
+func init() {
+	SetJSONCodec(StdJSONCodec{})
+}
+
+// SetJSONCodec 替换全局的JSON编解码器，应在程序启动时调用，替换后Json、MarshalJson、WriteJson以及返回结果的写入都会使用新的编解码器
+func SetJSONCodec(c JSONCodec) {
+	jsonCodec.Store(&c)
+}
+
+// Codec 返回当前使用的JSON编解码器
+func Codec() JSONCodec {
+	return *jsonCodec.Load().(*JSONCodec)
+}
+
+// maxPooledBuffer 超过该容量的缓冲区不放回池中，避免偶发的大结果长期占用内存
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// encodeJSON 使用当前编解码器将v编码到buf，不包含末尾的换行符
+func encodeJSON(buf *bytes.Buffer, v interface{}, indent bool) error {
+	enc := Codec().NewEncoder(buf)
+	if indent {
+		enc.SetIndent("", "\t")
+	}
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	if n := buf.Len(); n > 0 && buf.Bytes()[n-1] == '\n' {
+		buf.Truncate(n - 1)
+	}
+	return nil
+}
+
+// MarshalJson 将v脱敏后序列化为JSON，indent为true时使用制表符缩进
+func MarshalJson(v interface{}, indent bool) ([]byte, error) {
+	v = Mask(v)
+	if indent {
+		return Codec().MarshalIndent(v, "", "\t")
+	}
+	return Codec().Marshal(v)
+}
+
+// WriteJson 使用池化的缓冲区将v脱敏并序列化后一次性写入w，序列化失败时不会向w写入任何内容
+func WriteJson(w io.Writer, v interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, Mask(v), false); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Json 将v脱敏后序列化为JSON字符串，序列化失败时记录日志并返回空字符串，需要处理错误时使用MarshalJson。
+// 可信的内部调用需要原始数据时使用Json(Trusted(v), indent)。
+func Json(v interface{}, indent bool) string {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, Mask(v), indent); err != nil {
+		log.Err(err).Msgf("序列化JSON发生错误[%T]", v)
+		return ""
+	}
+	return buf.String()
+}
+
+// decodeJSON 读取r的全部内容并使用当前编解码器解码，部分编解码器解码后仍引用输入数据(如零拷贝的字符串)，
+// 因此输入不使用池化的缓冲区
+func decodeJSON(r io.Reader, v interface{}) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return Codec().Unmarshal(data, v)
+}
@@ -0,0 +1,61 @@
+//go:build jsoniter
+
+package common_test
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aluka-7/common"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// jsoniterCodec 基于jsoniter的编解码器，与标准库的输出兼容
+type jsoniterCodec struct{}
+
+var jsoniterStd = jsoniter.ConfigCompatibleWithStandardLibrary
+
+func (jsoniterCodec) Marshal(v interface{}) ([]byte, error) {
+	return jsoniterStd.Marshal(v)
+}
+
+func (jsoniterCodec) MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return jsoniterStd.MarshalIndent(v, prefix, indent)
+}
+
+func (jsoniterCodec) Unmarshal(data []byte, v interface{}) error {
+	return jsoniterStd.Unmarshal(data, v)
+}
+
+func (jsoniterCodec) NewEncoder(w io.Writer) common.JSONEncoder {
+	return jsoniterStd.NewEncoder(w)
+}
+
+// BenchmarkJsonCodec 对比标准库与jsoniter编解码器下的序列化开销，运行方式：
+//
+//	go test -tags jsoniter -run ^$ -bench BenchmarkJsonCodec .
+func BenchmarkJsonCodec(b *testing.B) {
+	page := benchPage()
+	data, _ := common.MarshalJson(page, false)
+	for _, c := range []struct {
+		name  string
+		codec common.JSONCodec
+	}{{"std", common.StdJSONCodec{}}, {"jsoniter", jsoniterCodec{}}} {
+		common.SetJSONCodec(c.codec)
+		b.Run(c.name+"/WriteJson", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = common.WriteJson(ioutil.Discard, page)
+			}
+		})
+		b.Run(c.name+"/Unmarshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var v common.PageResult
+				_ = common.Codec().Unmarshal(data, &v)
+			}
+		})
+	}
+	common.SetJSONCodec(common.StdJSONCodec{})
+}
@@ -0,0 +1,105 @@
+package common_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// countingCodec 统计调用次数的编解码器，用于验证编解码器可以替换
+type countingCodec struct {
+	common.StdJSONCodec
+	encodes int32
+}
+
+func (c *countingCodec) NewEncoder(w io.Writer) common.JSONEncoder {
+	atomic.AddInt32(&c.encodes, 1)
+	return c.StdJSONCodec.NewEncoder(w)
+}
+
+func TestJsonCodec(t *testing.T) {
+	Convey("Test JSON Codec", t, func() {
+		Convey("Test error returning variant", func() {
+			_, err := common.MarshalJson(math.Inf(1), false)
+			So(err, ShouldNotBeNil)
+			So(common.Json(math.Inf(1), false), ShouldEqual, "")
+		})
+		Convey("Test output matches encoding/json", func() {
+			v := map[string]interface{}{"html": "<a href=\"x\">&</a>", "list": []int{1, 2}}
+			std, _ := json.Marshal(v)
+			So(common.Json(v, false), ShouldEqual, string(std))
+			std, _ = json.MarshalIndent(v, "", "\t")
+			So(common.Json(v, true), ShouldEqual, string(std))
+		})
+		Convey("Test codec can be replaced", func() {
+			c := &countingCodec{}
+			common.SetJSONCodec(c)
+			defer common.SetJSONCodec(common.StdJSONCodec{})
+			So(common.Json(common.OK(), false), ShouldContainSubstring, `"code":200`)
+			So(atomic.LoadInt32(&c.encodes), ShouldEqual, 1)
+		})
+	})
+}
+
+// benchPage 构造接近真实场景的分页结果：100条包含多个字段的记录
+func benchPage() common.PageResult {
+	type item struct {
+		Id        int64    `json:"id"`
+		Name      string   `json:"name"`
+		Mobile    string   `json:"mobile"`
+		Email     string   `json:"email"`
+		Level     int      `json:"level"`
+		Tags      []string `json:"tags"`
+		CreatedAt string   `json:"createdAt"`
+	}
+	list := make([]item, 100)
+	for i := range list {
+		list[i] = item{
+			Id:        int64(i + 1),
+			Name:      "用户" + strconv.Itoa(i),
+			Mobile:    "13600009714",
+			Email:     "user" + strconv.Itoa(i) + "@example.com",
+			Level:     i % 5,
+			Tags:      []string{"vip", "new"},
+			CreatedAt: "2022-08-29T10:00:00+08:00",
+		}
+	}
+	p := common.NewPagination(1, 100, 0)
+	p.SetTotalRecord(1000)
+	return common.NewPageResult[any](p, list)
+}
+
+func BenchmarkJson(b *testing.B) {
+	page := benchPage()
+	b.Run("json.Marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = json.Marshal(page)
+		}
+	})
+	b.Run("common.MarshalJson", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = common.MarshalJson(page, false)
+		}
+	})
+	b.Run("common.Json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = common.Json(page, false)
+		}
+	})
+	b.Run("common.WriteJson", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = common.WriteJson(ioutil.Discard, page)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Decoded 不校验签名解码后的token内容
+type Decoded struct {
+	Header map[string]interface{} // JWT头部，如alg、typ等
+	Claims map[string]interface{} // JWT的全部声明
+}
+
+// Decode 不校验签名解码token，用于调试和排查问题，不能作为认证依据。
+func Decode(tokenString string) (*Decoded, error) {
+	claims := jwt.MapClaims{}
+	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoded{Header: token.Header, Claims: claims}, nil
+}
+
+// Reason 将VerifyToken返回的错误转换为简短的原因描述，err为nil时返回空字符串。
+func Reason(err error) string {
+	if err == nil {
+		return ""
+	}
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return "invalid"
+	}
+	switch {
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		return "malformed"
+	case ve.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0:
+		return "signature_invalid"
+	case ve.Errors&jwt.ValidationErrorExpired != 0:
+		return "expired"
+	case ve.Errors&(jwt.ValidationErrorNotValidYet|jwt.ValidationErrorIssuedAt) != 0:
+		return "not_yet_valid"
+	case ve.Errors&jwt.ValidationErrorAudience != 0:
+		return "audience_mismatch"
+	case ve.Errors&jwt.ValidationErrorIssuer != 0:
+		return "issuer_mismatch"
+	}
+	return "invalid"
+}
+
+// NewIntrospectionHandler 创建RFC 7662风格的token自省接口，使用TokenProvider.VerifyToken校验token。
+// 请求使用POST表单参数token，返回active表示token是否有效，有效时同时返回全部声明，
+// 无效时只返回active和reason，不回显未经校验的声明，离线查看未校验的内容使用jwtinspect命令。
+// 该接口会暴露token内容，只应部署在内部网络并做好访问控制。
+// @param           tp          已加载密钥的TokenProvider
+// @param           aud         期望的JWT token 的收件人
+// @param           iss         期望的JWT token 的发件人
+func NewIntrospectionHandler(tp TokenProvider, aud, iss string) http.Handler {
+	return &introspectionHandler{tp: tp, aud: aud, iss: iss}
+}
+
+type introspectionHandler struct {
+	tp       TokenProvider
+	aud, iss string
+}
+
+func (h *introspectionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	tokenString := strings.TrimSpace(r.PostFormValue("token"))
+	if tokenString == "" {
+		http.Error(w, "缺少token参数", http.StatusBadRequest)
+		return
+	}
+	resp := make(map[string]interface{})
+	if _, _, err := h.tp.VerifyToken(tokenString, h.aud, h.iss); err != nil {
+		resp["active"], resp["reason"] = false, Reason(err)
+	} else {
+		if d, err := Decode(tokenString); err == nil {
+			for k, v := range d.Claims {
+				resp[k] = v
+			}
+		}
+		resp["active"] = true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,57 @@
+package jwt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIntrospection(t *testing.T) {
+	Convey("Test Introspection Handler", t, func() {
+		jp := &jwtProvider{}
+		jp.Load("buzkd&yshKl#Si", 3600)
+		h := NewIntrospectionHandler(jp, "gateway", "web")
+		introspect := func(token string) map[string]interface{} {
+			req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(url.Values{"token": {token}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			resp := make(map[string]interface{})
+			So(json.Unmarshal(rec.Body.Bytes(), &resp), ShouldBeNil)
+			return resp
+		}
+
+		Convey("Test active token", func() {
+			signed := jp.CreateToken("gateway", "web", "sub", "107", time.Now().Unix(), UserClaims{UId: 1})
+			resp := introspect(signed)
+			So(resp["active"], ShouldBeTrue)
+			So(resp["jti"], ShouldEqual, "107")
+			So(resp["u_id"], ShouldEqual, 1)
+		})
+		Convey("Test audience mismatch", func() {
+			signed := jp.CreateToken("pay", "web", "sub", "107", time.Now().Unix(), UserClaims{})
+			resp := introspect(signed)
+			So(resp["active"], ShouldBeFalse)
+			So(resp["reason"], ShouldEqual, "audience_mismatch")
+			So(resp, ShouldHaveLength, 2)
+		})
+		Convey("Test forged token claims are not echoed", func() {
+			other := &jwtProvider{}
+			other.Load("another-secret", 3600)
+			resp := introspect(other.CreateToken("gateway", "web", "admin", "107", time.Now().Unix(), UserClaims{UId: 1}))
+			So(resp, ShouldResemble, map[string]interface{}{"active": false, "reason": "signature_invalid"})
+		})
+		Convey("Test malformed token", func() {
+			resp := introspect("not-a-token")
+			So(resp["active"], ShouldBeFalse)
+			So(resp["reason"], ShouldEqual, "malformed")
+		})
+	})
+}
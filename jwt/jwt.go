@@ -14,11 +14,11 @@ import (
 )
 
 type UserClaims struct {
-	UId    int64  `json:"u_id"`    // 用户id
-	UName  string `json:"u_name"`  // 用户名
-	ULevel int    `json:"u_level"` // 用户等级
-	Avater string `json:"avater"`  // 用户头像
-	Mobile string `json:"mobile"`  // 用户手机号
+	UId    int64  `json:"u_id"`                 // 用户id
+	UName  string `json:"u_name"`               // 用户名
+	ULevel int    `json:"u_level"`              // 用户等级
+	Avater string `json:"avater"`               // 用户头像
+	Mobile string `json:"mobile" mask:"mobile"` // 用户手机号，通过common.Json等序列化时脱敏
 }
 type tokenStandardClaims struct {
 	jwt.StandardClaims
@@ -0,0 +1,156 @@
+package jwt
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrTokenConsumed   = errors.New("token已被使用")
+	ErrPurposeMismatch = errors.New("token用途不匹配")
+	ErrBindingChanged  = errors.New("token绑定的信息已变更")
+	ErrBindingRequired = errors.New("token未绑定所需的信息")
+)
+
+// consumeSweepInterval 内存存储清理过期记录的最小间隔
+const consumeSweepInterval = time.Minute
+
+// ConsumeStore 一次性token的使用记录存储，多实例部署时需要使用共享存储(如redis的SETNX)实现。
+type ConsumeStore interface {
+	// Consume 原子地将jti标记为已使用，首次标记返回true，已经使用过返回false，until之后该记录可以被清理。
+	Consume(ctx context.Context, jti string, until time.Time) (bool, error)
+}
+
+// NewMemoryConsumeStore 创建基于内存的一次性token使用记录存储，适用于单实例部署和测试。
+func NewMemoryConsumeStore() ConsumeStore {
+	return &memoryConsumeStore{used: make(map[string]time.Time)}
+}
+
+type memoryConsumeStore struct {
+	mu        sync.Mutex
+	used      map[string]time.Time
+	lastSweep time.Time // 上次清理过期记录的时间
+}
+
+// Consume 标记jti为已使用，过期记录的清理按consumeSweepInterval间隔进行，避免每次调用都遍历所有记录
+func (s *memoryConsumeStore) Consume(ctx context.Context, jti string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := jwt.TimeFunc()
+	if now.Sub(s.lastSweep) >= consumeSweepInterval {
+		for k, t := range s.used {
+			if now.After(t) {
+				delete(s.used, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	if t, ok := s.used[jti]; ok && !now.After(t) {
+		return false, nil
+	}
+	s.used[jti] = until
+	return true, nil
+}
+
+type purposeClaims struct {
+	jwt.StandardClaims
+	Purpose string `json:"purpose"`        // token用途，如email_verify、password_reset
+	Binding string `json:"bind,omitempty"` // 绑定信息的摘要
+}
+
+// PurposeTokenProvider 用途限定的一次性token，用于邮箱验证、重置密码等链接。
+// token携带purpose声明，只能使用一次，有效期由签发时单独指定，
+// 可以绑定当前密码摘要或邮箱等信息，这些信息变更后token随即失效。
+type PurposeTokenProvider struct {
+	secret []byte
+	store  ConsumeStore
+}
+
+// NewPurposeTokenProvider 创建用途限定token的签发器，建议使用与登录token不同的密钥。
+func NewPurposeTokenProvider(secret string, store ConsumeStore) *PurposeTokenProvider {
+	if len(secret) < 8 {
+		panic("The key length of jwt must be greater than or equal to 8 bits")
+	}
+	return &PurposeTokenProvider{secret: []byte(secret), store: store}
+}
+
+// Create
+// @title Create
+// @description     签发用途限定的一次性token
+// @param           purpose     token用途
+// @param           sub         JWT token 的主体，一般为用户id
+// @param           ttl         token的有效时长，与登录token的过期时间无关，必须大于0
+// @param           binding     需要绑定的信息，如当前密码的哈希或邮箱，为空时不绑定
+// @return          signed      JWT token 签名
+func (p *PurposeTokenProvider) Create(purpose, sub string, ttl time.Duration, binding string) (signed string, err error) {
+	if ttl <= 0 {
+		return "", fmt.Errorf("token有效时长必须大于0: %s", ttl)
+	}
+	now := jwt.TimeFunc()
+	pc := &purposeClaims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: now.Add(ttl).Unix(),
+			IssuedAt:  now.Unix(),
+			Subject:   sub,
+			Id:        randomJti(),
+		},
+		Purpose: purpose,
+	}
+	if binding != "" {
+		pc.Binding = p.bind(purpose, binding)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, pc).SignedString(p.secret)
+}
+
+// Consume
+// @title Consume
+// @description     校验并消费用途限定的一次性token，签名、有效期、用途和绑定信息都校验通过后才会标记为已使用
+// @param           tokenString JWT token
+// @param           purpose     期望的token用途
+// @param           binding     当前的绑定信息，不为空时token必须在签发时绑定了相同的信息
+// @return          sub         JWT token 的主体
+// @return          err         错误
+func (p *PurposeTokenProvider) Consume(ctx context.Context, tokenString, purpose, binding string) (sub string, err error) {
+	pc := &purposeClaims{}
+	_, err = jwt.ParseWithClaims(tokenString, pc, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return p.secret, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if pc.Purpose == "" || pc.Purpose != purpose {
+		return "", ErrPurposeMismatch
+	}
+	if pc.Binding == "" && binding != "" {
+		return "", ErrBindingRequired
+	}
+	if pc.Binding != "" && !hmac.Equal([]byte(pc.Binding), []byte(p.bind(purpose, binding))) {
+		return "", ErrBindingChanged
+	}
+	ok, err := p.store.Consume(ctx, pc.Id, time.Unix(pc.ExpiresAt, 0))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrTokenConsumed
+	}
+	return pc.Subject, nil
+}
+
+// bind 计算绑定信息的摘要，token中只保存摘要，不会泄露密码哈希或邮箱
+func (p *PurposeTokenProvider) bind(purpose, value string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(purpose + "\x00" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPurposeToken(t *testing.T) {
+	Convey("Test Purpose Token", t, func() {
+		ctx := context.Background()
+		p := NewPurposeTokenProvider("buzkd&yshKl#Si", NewMemoryConsumeStore())
+
+		Convey("Test token can be consumed only once", func() {
+			signed, err := p.Create("email_verify", "107", 10*time.Minute, "")
+			So(err, ShouldBeNil)
+			sub, err := p.Consume(ctx, signed, "email_verify", "")
+			So(err, ShouldBeNil)
+			So(sub, ShouldEqual, "107")
+			_, err = p.Consume(ctx, signed, "email_verify", "")
+			So(err, ShouldEqual, ErrTokenConsumed)
+		})
+		Convey("Test purpose mismatch does not consume", func() {
+			signed, _ := p.Create("email_verify", "107", 10*time.Minute, "")
+			_, err := p.Consume(ctx, signed, "password_reset", "")
+			So(err, ShouldEqual, ErrPurposeMismatch)
+			_, err = p.Consume(ctx, signed, "email_verify", "")
+			So(err, ShouldBeNil)
+		})
+		Convey("Test binding change invalidates token", func() {
+			signed, _ := p.Create("password_reset", "107", 10*time.Minute, "old-hash")
+			_, err := p.Consume(ctx, signed, "password_reset", "new-hash")
+			So(err, ShouldEqual, ErrBindingChanged)
+			_, err = p.Consume(ctx, signed, "password_reset", "old-hash")
+			So(err, ShouldBeNil)
+		})
+		Convey("Test unbound token is rejected when a binding is required", func() {
+			signed, _ := p.Create("password_reset", "107", 10*time.Minute, "")
+			_, err := p.Consume(ctx, signed, "password_reset", "old-hash")
+			So(err, ShouldEqual, ErrBindingRequired)
+		})
+		Convey("Test non positive ttl is rejected", func() {
+			_, err := p.Create("email_verify", "107", 0, "")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Test memory store sweeps expired records periodically", func() {
+			now := time.Now()
+			jwt.TimeFunc = func() time.Time { return now }
+			defer func() { jwt.TimeFunc = time.Now }()
+			s := NewMemoryConsumeStore().(*memoryConsumeStore)
+			_, _ = s.Consume(ctx, "a", now.Add(time.Second))
+			now = now.Add(2 * time.Second)
+			ok, _ := s.Consume(ctx, "b", now.Add(time.Second))
+			So(ok, ShouldBeTrue)
+			So(len(s.used), ShouldEqual, 2)
+			now = now.Add(consumeSweepInterval)
+			_, _ = s.Consume(ctx, "c", now.Add(time.Second))
+			So(len(s.used), ShouldEqual, 1)
+		})
+		Convey("Test login token is rejected", func() {
+			jp := jwtProvider{}
+			jp.Load("buzkd&yshKl#Si", 3600)
+			signed := jp.CreateToken("gateway", "web", "107", "1", time.Now().Unix(), UserClaims{})
+			_, err := p.Consume(ctx, signed, "", "")
+			So(err, ShouldEqual, ErrPurposeMismatch)
+		})
+	})
+}
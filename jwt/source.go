@@ -0,0 +1,224 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	defaultRefreshBefore = time.Minute      // 默认在过期前1分钟开始后台续签
+	defaultExpiryDelta   = 10 * time.Second // token剩余有效期小于该值时视为已过期
+	minRetryBackoff      = time.Second      // 签发失败后第一次重试前的等待时间，之后每次翻倍
+	maxRetryBackoff      = time.Minute      // 签发失败后重试等待时间的上限
+)
+
+// Minter 服务间调用的token签发器，按接收者(aud)签发或从认证中心获取token。
+type Minter interface {
+	// Mint 为给定的接收者签发token，返回签名后的token和其过期时间。
+	Mint(ctx context.Context, aud string) (token string, exp time.Time, err error)
+}
+
+// MinterFunc 将普通函数适配为Minter
+type MinterFunc func(ctx context.Context, aud string) (string, time.Time, error)
+
+func (f MinterFunc) Mint(ctx context.Context, aud string) (string, time.Time, error) {
+	return f(ctx, aud)
+}
+
+// ProviderMinter 使用本地的TokenProvider签发服务间token，每次签发使用随机的jti。
+// @param           tp          已加载密钥的TokenProvider
+// @param           iss         JWT token 的发件人，一般为当前服务名
+// @param           sub         JWT token 的主体
+// @param           claims      附带的用户信息
+func ProviderMinter(tp TokenProvider, iss, sub string, claims UserClaims) Minter {
+	return MinterFunc(func(ctx context.Context, aud string) (string, time.Time, error) {
+		signed := tp.CreateToken(aud, iss, sub, randomJti(), jwt.TimeFunc().Unix(), claims)
+		if signed == "" {
+			return "", time.Time{}, errors.New("签发服务间token失败")
+		}
+		exp, err := expiresAt(signed)
+		return signed, exp, err
+	})
+}
+
+// TokenSource 按接收者缓存服务间token，在token过期前后台续签，同一接收者同时只会有一次签发请求。
+type TokenSource struct {
+	minter  Minter
+	refresh time.Duration // 距离过期多久时开始后台续签
+	mu      sync.Mutex
+	tokens  map[string]*cachedToken
+}
+
+type cachedToken struct {
+	token    string
+	exp      time.Time
+	lifetime time.Duration // 签发时token的有效期，续签时间不超过有效期的一半
+	inflight *mintCall     // 正在进行中的签发，为nil表示当前没有签发
+	failures int           // 连续签发失败的次数
+	retryAt  time.Time     // 签发失败后下一次允许重试的时间
+	lastErr  error         // 最后一次签发失败的原因
+}
+
+type mintCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// NewTokenSource 创建服务间token源，refreshBefore为距离过期多久时开始后台续签，小于等于0时使用默认值1分钟，
+// 超过token有效期的一半时按有效期的一半计算，避免有效期较短的token刚签发就开始续签。
+// 签发失败后按指数退避重试，退避期间续签不会再次发起，没有可用token的调用直接返回最后一次的错误。
+func NewTokenSource(m Minter, refreshBefore time.Duration) *TokenSource {
+	if refreshBefore <= 0 {
+		refreshBefore = defaultRefreshBefore
+	}
+	return &TokenSource{minter: m, refresh: refreshBefore, tokens: make(map[string]*cachedToken)}
+}
+
+// Token 获取给定接收者的token，缓存的token有效时直接返回，临近过期时触发后台续签，已过期时同步等待签发结果。
+func (ts *TokenSource) Token(ctx context.Context, aud string) (string, error) {
+	ts.mu.Lock()
+	ct, ok := ts.tokens[aud]
+	if !ok {
+		ct = &cachedToken{}
+		ts.tokens[aud] = ct
+	}
+	now := jwt.TimeFunc()
+	remain := ct.exp.Sub(now)
+	if ct.token != "" && remain > defaultExpiryDelta {
+		if remain <= ts.refreshBefore(ct) && ct.inflight == nil && !now.Before(ct.retryAt) {
+			ts.startMint(ct, aud)
+		}
+		token := ct.token
+		ts.mu.Unlock()
+		return token, nil
+	}
+	call := ct.inflight
+	if call == nil {
+		if now.Before(ct.retryAt) {
+			err := ct.lastErr
+			ts.mu.Unlock()
+			return "", err
+		}
+		call = ts.startMint(ct, aud)
+	}
+	ts.mu.Unlock()
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// startMint 在后台发起一次签发，调用方必须持有ts.mu。
+// 签发使用独立的上下文，避免某个调用方取消请求后影响其他等待同一结果的调用方。
+func (ts *TokenSource) startMint(ct *cachedToken, aud string) *mintCall {
+	call := &mintCall{done: make(chan struct{})}
+	ct.inflight = call
+	go func() {
+		token, exp, err := ts.minter.Mint(context.Background(), aud)
+		ts.mu.Lock()
+		if now := jwt.TimeFunc(); err == nil {
+			ct.token, ct.exp, ct.lifetime = token, exp, exp.Sub(now)
+			ct.failures, ct.retryAt, ct.lastErr = 0, time.Time{}, nil
+		} else {
+			ct.failures++
+			ct.retryAt, ct.lastErr = now.Add(retryBackoff(ct.failures)), err
+			log.Err(err).Msgf("签发服务间token失败[%s]，第%d次", aud, ct.failures)
+		}
+		ct.inflight = nil
+		call.token, call.err = ct.token, err
+		ts.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// refreshBefore 返回距离过期多久时开始续签，不超过token有效期的一半，调用方必须持有ts.mu
+func (ts *TokenSource) refreshBefore(ct *cachedToken) time.Duration {
+	if half := ct.lifetime / 2; half > 0 && half < ts.refresh {
+		return half
+	}
+	return ts.refresh
+}
+
+// retryBackoff 返回连续第failures次签发失败后的等待时间
+func retryBackoff(failures int) time.Duration {
+	wait := minRetryBackoff << uint(failures-1)
+	if wait <= 0 || wait > maxRetryBackoff {
+		wait = maxRetryBackoff
+	}
+	return wait
+}
+
+// PerRPCCredentials 返回gRPC的调用凭证，每次调用时在metadata中附带给定接收者的token。
+func (ts *TokenSource) PerRPCCredentials(aud string, requireTLS bool) credentials.PerRPCCredentials {
+	return &rpcCredentials{ts: ts, aud: aud, requireTLS: requireTLS}
+}
+
+type rpcCredentials struct {
+	ts         *TokenSource
+	aud        string
+	requireTLS bool
+}
+
+func (c *rpcCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := c.ts.Token(ctx, c.aud)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+func (c *rpcCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
+
+// RoundTripper 返回在每个HTTP请求的Authorization头中附带给定接收者token的http.RoundTripper，base为nil时使用http.DefaultTransport。
+func (ts *TokenSource) RoundTripper(aud string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &roundTripper{ts: ts, aud: aud, base: base}
+}
+
+type roundTripper struct {
+	ts   *TokenSource
+	aud  string
+	base http.RoundTripper
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := rt.ts.Token(req.Context(), rt.aud)
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return rt.base.RoundTrip(r)
+}
+
+// expiresAt 不校验签名读取token的过期时间
+func expiresAt(signed string) (time.Time, error) {
+	var claims jwt.StandardClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(signed, &claims); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(claims.ExpiresAt, 0), nil
+}
+
+func randomJti() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,101 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTokenSource(t *testing.T) {
+	Convey("Test Token Source", t, func() {
+		jp := &jwtProvider{}
+		jp.Load("buzkd&yshKl#Si", 3600)
+		var minted int32
+		base := ProviderMinter(jp, "order", "order-service", UserClaims{})
+		ts := NewTokenSource(MinterFunc(func(ctx context.Context, aud string) (string, time.Time, error) {
+			atomic.AddInt32(&minted, 1)
+			return base.Mint(ctx, aud)
+		}), time.Minute)
+
+		Convey("Test concurrent callers share one mint", func() {
+			var wg sync.WaitGroup
+			errs := make(chan error, 20)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := ts.Token(context.Background(), "user")
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				So(err, ShouldBeNil)
+			}
+			So(atomic.LoadInt32(&minted), ShouldEqual, 1)
+
+			token, err := ts.Token(context.Background(), "user")
+			So(err, ShouldBeNil)
+			_, _, err = jp.VerifyToken(token, "user", "order")
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&minted), ShouldEqual, 1)
+		})
+		Convey("Test failed mints back off", func() {
+			var calls int32
+			failing := NewTokenSource(MinterFunc(func(ctx context.Context, aud string) (string, time.Time, error) {
+				atomic.AddInt32(&calls, 1)
+				return "", time.Time{}, errors.New("auth server down")
+			}), time.Minute)
+			_, err := failing.Token(context.Background(), "user")
+			So(err, ShouldNotBeNil)
+			_, err = failing.Token(context.Background(), "user")
+			So(err, ShouldNotBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			So(retryBackoff(1), ShouldEqual, minRetryBackoff)
+			So(retryBackoff(100), ShouldEqual, maxRetryBackoff)
+		})
+		Convey("Test refresh starts at half of a short lifetime", func() {
+			var calls int32
+			short := NewTokenSource(MinterFunc(func(ctx context.Context, aud string) (string, time.Time, error) {
+				atomic.AddInt32(&calls, 1)
+				return "short", time.Now().Add(30 * time.Second), nil
+			}), time.Minute)
+			_, _ = short.Token(context.Background(), "user")
+			token, err := short.Token(context.Background(), "user")
+			So(err, ShouldBeNil)
+			So(token, ShouldEqual, "short")
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		})
+		Convey("Test tokens are cached per audience", func() {
+			_, _ = ts.Token(context.Background(), "user")
+			_, _ = ts.Token(context.Background(), "pay")
+			So(atomic.LoadInt32(&minted), ShouldEqual, 2)
+		})
+		Convey("Test round tripper sets authorization", func() {
+			var auth string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth = r.Header.Get("Authorization")
+			}))
+			defer srv.Close()
+			client := &http.Client{Transport: ts.RoundTripper("user", nil)}
+			resp, err := client.Get(srv.URL)
+			So(err, ShouldBeNil)
+			_ = resp.Body.Close()
+			token, _ := ts.Token(context.Background(), "user")
+			So(auth, ShouldEqual, "Bearer "+token)
+		})
+		Convey("Test per rpc credentials", func() {
+			md, err := ts.PerRPCCredentials("user", false).GetRequestMetadata(context.Background())
+			So(err, ShouldBeNil)
+			So(md["authorization"], ShouldStartWith, "Bearer ")
+		})
+	})
+}
@@ -0,0 +1,162 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrTenantMismatch = errors.New("JWT租户不匹配")
+	ErrUnknownTenant  = errors.New("未知的租户")
+)
+
+// Tenant 租户的签发配置，每个租户使用独立的签发者和密钥
+type Tenant struct {
+	ID     string // 租户标识，写入token的tid声明
+	Issuer string // 该租户的JWT签发者
+	Secret []byte // 该租户的签名密钥
+}
+
+// TenantResolver 根据租户标识获取租户的签发配置，租户不存在时返回ErrUnknownTenant。
+type TenantResolver interface {
+	Resolve(tenant string) (Tenant, error)
+}
+
+// TenantResolverFunc 将普通函数适配为TenantResolver
+type TenantResolverFunc func(tenant string) (Tenant, error)
+
+func (f TenantResolverFunc) Resolve(tenant string) (Tenant, error) {
+	return f(tenant)
+}
+
+// StaticTenants 使用固定的租户列表创建TenantResolver
+func StaticTenants(tenants ...Tenant) TenantResolver {
+	m := make(map[string]Tenant, len(tenants))
+	for _, t := range tenants {
+		if len(t.Secret) < 8 {
+			panic("The key length of jwt must be greater than or equal to 8 bits")
+		}
+		m[t.ID] = t
+	}
+	return TenantResolverFunc(func(tenant string) (Tenant, error) {
+		if t, ok := m[tenant]; ok {
+			return t, nil
+		}
+		return Tenant{}, ErrUnknownTenant
+	})
+}
+
+type tenantClaims struct {
+	jwt.StandardClaims
+	UserClaims
+	Tenant string `json:"tid"` // 租户标识
+}
+
+// TenantTokenProvider 多租户的token签发和校验，签发者和密钥由TenantResolver按租户决定，
+// 一个租户签发的token在其他租户的路由上校验必然失败。
+type TenantTokenProvider struct {
+	resolver TenantResolver
+	exp      int // 过期时间(秒)
+}
+
+// NewTenantTokenProvider 创建多租户的token签发器，exp为token的有效时长(秒)。
+func NewTenantTokenProvider(resolver TenantResolver, exp int) *TenantTokenProvider {
+	return &TenantTokenProvider{resolver: resolver, exp: exp}
+}
+
+// CreateToken
+// @title CreateToken
+// @description     为租户签发token，签发者使用租户配置的Issuer
+// @param           tenant      租户标识
+// @param           aud         JWT token 的收件人
+// @param           sub         JWT token 的主体
+// @param           jti         JWT token 的唯一标识符
+// @param           nbf         JWT token 的生效时间
+// @return          signed      JWT token 签名
+func (p *TenantTokenProvider) CreateToken(tenant, aud, sub, jti string, nbf int64, claims UserClaims) (signed string, err error) {
+	t, err := p.resolver.Resolve(tenant)
+	if err != nil {
+		return "", err
+	}
+	now := jwt.TimeFunc()
+	tc := &tenantClaims{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  aud,
+			ExpiresAt: now.Add(time.Second * time.Duration(p.exp)).Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    t.Issuer,
+			NotBefore: nbf,
+			Subject:   sub,
+			Id:        jti,
+		},
+		UserClaims: claims,
+		Tenant:     tenant,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, tc).SignedString(t.Secret)
+}
+
+// VerifyToken
+// @title VerifyToken
+// @description     在租户的路由上校验token，先确认token的租户与路由租户一致，再使用该租户的密钥校验签名和签发者
+// @param           tenant      当前路由所属的租户
+// @param           tokenString JWT token
+// @param           aud         JWT token 的收件人
+// @return          jti         JWT token 的唯一标识符
+// @return          claims      用户信息
+// @return          err         错误
+func (p *TenantTokenProvider) VerifyToken(tenant, tokenString, aud string) (jti string, userClaims UserClaims, err error) {
+	var issuer string
+	tc := &tenantClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, tc, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		// 此时签名尚未校验，只用tid选择密钥，不能信任其他声明
+		if tc.Tenant != tenant {
+			return nil, ErrTenantMismatch
+		}
+		t, err := p.resolver.Resolve(tenant)
+		if err != nil {
+			return nil, err
+		}
+		issuer = t.Issuer
+		return t.Secret, nil
+	})
+	if err != nil {
+		// jwt-go的ValidationError不支持errors.Is，将租户相关的错误还原出来
+		if ve, ok := err.(*jwt.ValidationError); ok && (ve.Inner == ErrTenantMismatch || ve.Inner == ErrUnknownTenant) {
+			err = ve.Inner
+		}
+		return jti, userClaims, err
+	}
+	if !token.Valid {
+		return tc.Id, userClaims, jwt.NewValidationError("JWT验证错误", jwt.ValidationErrorClaimsInvalid)
+	}
+	if !tc.VerifyAudience(aud, true) {
+		log.Info().Msgf("JWT接收者不匹配[%s]", aud)
+		return tc.Id, tc.UserClaims, jwt.NewValidationError("JWT接收者不匹配", jwt.ValidationErrorAudience)
+	}
+	if !tc.VerifyIssuer(issuer, true) {
+		log.Info().Msgf("JWT签发者不匹配[%s]", issuer)
+		return tc.Id, tc.UserClaims, jwt.NewValidationError("JWT签发者不匹配", jwt.ValidationErrorIssuer)
+	}
+	return tc.Id, tc.UserClaims, nil
+}
+
+type tenantKey struct{}
+
+// WithTenant 将当前请求所属的租户保存到上下文中
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext 从上下文中获取当前请求所属的租户
+func TenantFromContext(ctx context.Context) (tenant string, ok bool) {
+	tenant, ok = ctx.Value(tenantKey{}).(string)
+	return
+}
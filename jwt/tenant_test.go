@@ -0,0 +1,57 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTenantToken(t *testing.T) {
+	Convey("Test Tenant Token", t, func() {
+		p := NewTenantTokenProvider(StaticTenants(
+			Tenant{ID: "a", Issuer: "https://a.example.com", Secret: []byte("tenant-a-secret")},
+			Tenant{ID: "b", Issuer: "https://b.example.com", Secret: []byte("tenant-b-secret")},
+		), 3600)
+		nbf := time.Now().Unix()
+
+		Convey("Test token verifies on its own tenant", func() {
+			signed, err := p.CreateToken("a", "gateway", "107", "1", nbf, UserClaims{UId: 107})
+			So(err, ShouldBeNil)
+			jti, claims, err := p.VerifyToken("a", signed, "gateway")
+			So(err, ShouldBeNil)
+			So(jti, ShouldEqual, "1")
+			So(claims.UId, ShouldEqual, 107)
+		})
+		Convey("Test token fails on another tenant", func() {
+			signed, _ := p.CreateToken("a", "gateway", "107", "1", nbf, UserClaims{})
+			_, _, err := p.VerifyToken("b", signed, "gateway")
+			So(errors.Is(err, ErrTenantMismatch), ShouldBeTrue)
+		})
+		Convey("Test forged tenant claim fails signature", func() {
+			forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &tenantClaims{
+				StandardClaims: jwt.StandardClaims{Audience: "gateway", Issuer: "https://b.example.com"},
+				Tenant:         "b",
+			})
+			signed, _ := forged.SignedString([]byte("tenant-a-secret"))
+			_, _, err := p.VerifyToken("b", signed, "gateway")
+			So(err, ShouldNotBeNil)
+			So(Reason(err), ShouldEqual, "signature_invalid")
+		})
+		Convey("Test unknown tenant", func() {
+			_, err := p.CreateToken("c", "gateway", "107", "1", nbf, UserClaims{})
+			So(err, ShouldEqual, ErrUnknownTenant)
+		})
+		Convey("Test tenant context", func() {
+			ctx := WithTenant(context.Background(), "a")
+			tenant, ok := TenantFromContext(ctx)
+			So(ok, ShouldBeTrue)
+			So(tenant, ShouldEqual, "a")
+			_, ok = TenantFromContext(context.Background())
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
@@ -0,0 +1,352 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// Masker 脱敏函数，输入原始值返回脱敏后的值
+type Masker func(s string) string
+
+var (
+	maskersMu sync.RWMutex
+	maskers   = map[string]Masker{
+		"mobile":   func(s string) string { return maskMiddle(s, 3, 4) },
+		"idcard":   func(s string) string { return maskMiddle(s, 3, 4) },
+		"bankcard": func(s string) string { return maskMiddle(s, 0, 4) },
+		"name":     func(s string) string { return maskMiddle(s, 1, 0) },
+		"email":    maskEmail,
+		"all":      func(s string) string { return "****" },
+	}
+	maskTypes sync.Map // reflect.Type -> bool，类型是否可能包含需要脱敏的字段
+)
+
+// RegisterMasker 注册自定义的脱敏函数，在结构体字段上使用`mask:"name"`标签引用，重复注册时panic
+func RegisterMasker(name string, m Masker) {
+	maskersMu.Lock()
+	defer maskersMu.Unlock()
+	if _, ok := maskers[name]; ok {
+		panic(fmt.Sprintf("脱敏函数[%s]重复注册", name))
+	}
+	maskers[name] = m
+}
+
+func lookupMasker(name string) (Masker, bool) {
+	maskersMu.RLock()
+	defer maskersMu.RUnlock()
+	m, ok := maskers[name]
+	return m, ok
+}
+
+// maskMiddle 保留前head个和后tail个字符，中间替换为*，长度不足时全部替换
+func maskMiddle(s string, head, tail int) string {
+	n := utf8.RuneCountInString(s)
+	if n == 0 {
+		return s
+	}
+	if n <= head+tail {
+		return strings.Repeat("*", n)
+	}
+	r := []rune(s)
+	return string(r[:head]) + strings.Repeat("*", n-head-tail) + string(r[n-tail:])
+}
+
+// maskEmail 保留邮箱用户名的首字符和域名，如a***@example.com
+func maskEmail(s string) string {
+	at := strings.LastIndex(s, "@")
+	if at <= 0 {
+		return maskMiddle(s, 1, 0)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return string(r) + "***" + s[at:]
+}
+
+// trusted 不需要脱敏的值
+type trusted struct {
+	v interface{}
+}
+
+func (t trusted) MarshalJSON() ([]byte, error) {
+	return Codec().Marshal(t.v)
+}
+
+// Trusted 标记v不需要脱敏，用于可信的内部调用，如Json(Trusted(v), false)
+func Trusted(v interface{}) interface{} {
+	return trusted{v}
+}
+
+type trustedKey struct{}
+
+// WithTrusted 标记当前请求为可信的内部调用，写入返回结果时不进行脱敏
+func WithTrusted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustedKey{}, true)
+}
+
+// IsTrusted 判断当前请求是否为可信的内部调用
+func IsTrusted(ctx context.Context) bool {
+	t, _ := ctx.Value(trustedKey{}).(bool)
+	return t
+}
+
+// Mask 根据结构体字段的mask标签对v进行脱敏，返回脱敏后的副本，不会修改v本身。
+// 标签的值为脱敏函数名，内置mobile、email、idcard、bankcard、name、all，只对string和*string字段生效。
+// v不包含需要脱敏的字段时原样返回；v中存在循环引用时返回的值在序列化时报错，不会输出未脱敏的数据。
+func Mask(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if _, ok := v.(trusted); ok {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	if !needsMask(rv.Type()) {
+		return v
+	}
+	m := &masker{visiting: make(map[visitKey]bool)}
+	mv, changed := m.value(rv)
+	if m.err != nil {
+		return maskError{m.err}
+	}
+	if changed {
+		return mv.Interface()
+	}
+	return v
+}
+
+// maskError 脱敏失败时返回的值，序列化时返回错误
+type maskError struct {
+	err error
+}
+
+func (e maskError) MarshalJSON() ([]byte, error) {
+	return nil, e.err
+}
+
+// EnableLogMasking 开启日志脱敏，通过zerolog的Interface记录的对象同样按mask标签进行脱敏。
+// 会替换全局的zerolog.InterfaceMarshalFunc，应在程序启动时调用一次。
+func EnableLogMasking() {
+	zerolog.InterfaceMarshalFunc = func(v interface{}) ([]byte, error) {
+		return Codec().Marshal(Mask(v))
+	}
+}
+
+// needsMask 判断类型是否可能包含需要脱敏的字段，interface类型需要在运行时判断
+func needsMask(t reflect.Type) bool {
+	if v, ok := maskTypes.Load(t); ok {
+		return v.(bool)
+	}
+	s := &typeScan{visiting: make(map[reflect.Type]bool), pending: make(map[reflect.Type]bool)}
+	need, _ := s.scan(t)
+	if !need {
+		// 从t出发没有找到需要脱敏的字段，扫描过程中所有待定的类型同样不需要脱敏
+		for pt := range s.pending {
+			maskTypes.Store(pt, false)
+		}
+	}
+	maskTypes.Store(t, need)
+	return need
+}
+
+// typeScan 单次needsMask调用的扫描状态，递归类型在扫描中的结果依赖于尚未完成的类型，
+// 这类待定结果只在确定后写入maskTypes，避免缓存中间结果
+type typeScan struct {
+	visiting map[reflect.Type]bool // 正在扫描的类型
+	pending  map[reflect.Type]bool // 扫描完成但结果依赖于正在扫描的类型
+}
+
+// scan 返回类型是否需要脱敏，pending为true时表示结果为待定的false
+func (s *typeScan) scan(t reflect.Type) (need, pending bool) {
+	if v, ok := maskTypes.Load(t); ok {
+		return v.(bool), false
+	}
+	if s.visiting[t] || s.pending[t] {
+		return false, true
+	}
+	s.visiting[t] = true
+	defer delete(s.visiting, t)
+	switch t.Kind() {
+	case reflect.Interface:
+		need = true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		need, pending = s.scan(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+			if _, ok := f.Tag.Lookup("mask"); ok {
+				need = true
+				break
+			}
+			n, p := s.scan(f.Type)
+			if n {
+				need = true
+				break
+			}
+			pending = pending || p
+		}
+	}
+	if need {
+		pending = false
+	}
+	if pending {
+		s.pending[t] = true
+	} else {
+		maskTypes.Store(t, need)
+	}
+	return need, pending
+}
+
+// visitKey 标识正在脱敏的引用值，用于检测循环引用
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// masker 单次Mask调用的脱敏状态
+type masker struct {
+	visiting map[visitKey]bool
+	err      error
+}
+
+// enter 标记引用值正在脱敏，出现循环引用时记录错误并返回false
+func (m *masker) enter(v reflect.Value) (visitKey, bool) {
+	k := visitKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		k.len = v.Len()
+	}
+	if m.visiting[k] {
+		m.err = fmt.Errorf("脱敏失败: 类型[%s]存在循环引用", v.Type())
+		return k, false
+	}
+	m.visiting[k] = true
+	return k, true
+}
+
+// value 返回脱敏后的副本，没有字段被脱敏时changed为false，调用方应继续使用原值
+func (m *masker) value(v reflect.Value) (reflect.Value, bool) {
+	if m.err != nil || !v.IsValid() || !needsMask(v.Type()) {
+		return v, false
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		if _, ok := v.Interface().(trusted); ok {
+			return v, false
+		}
+		mv, changed := m.value(v.Elem())
+		if !changed {
+			return v, false
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(mv)
+		return out, true
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		k, ok := m.enter(v)
+		if !ok {
+			return v, false
+		}
+		defer delete(m.visiting, k)
+		mv, changed := m.value(v.Elem())
+		if !changed {
+			return v, false
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(mv)
+		return out, true
+	case reflect.Slice, reflect.Array:
+		var out reflect.Value
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return v, false
+			}
+			k, ok := m.enter(v)
+			if !ok {
+				return v, false
+			}
+			defer delete(m.visiting, k)
+			out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		} else {
+			out = reflect.New(v.Type()).Elem()
+		}
+		masked := false
+		for i := 0; i < v.Len(); i++ {
+			mv, changed := m.value(v.Index(i))
+			masked = masked || changed
+			out.Index(i).Set(mv)
+		}
+		return out, masked
+	case reflect.Map:
+		if v.IsNil() {
+			return v, false
+		}
+		k, ok := m.enter(v)
+		if !ok {
+			return v, false
+		}
+		defer delete(m.visiting, k)
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		masked := false
+		iter := v.MapRange()
+		for iter.Next() {
+			mv, changed := m.value(iter.Value())
+			masked = masked || changed
+			out.SetMapIndex(iter.Key(), mv)
+		}
+		return out, masked
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		masked := false
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f, fv := t.Field(i), out.Field(i)
+			if !fv.CanSet() {
+				continue
+			}
+			if name, ok := f.Tag.Lookup("mask"); ok {
+				if maskField(fv, name) {
+					masked = true
+				}
+				continue
+			}
+			if mv, changed := m.value(fv); changed {
+				fv.Set(mv)
+				masked = true
+			}
+		}
+		return out, masked
+	}
+	return v, false
+}
+
+// maskField 对string或*string字段应用脱敏函数，未注册的脱敏函数按all处理，避免因拼写错误泄露数据
+func maskField(fv reflect.Value, name string) bool {
+	m, ok := lookupMasker(name)
+	if !ok {
+		m, _ = lookupMasker("all")
+	}
+	switch {
+	case fv.Kind() == reflect.String:
+		fv.SetString(m(fv.String()))
+		return true
+	case fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.String && !fv.IsNil():
+		s := m(fv.Elem().String())
+		fv.Set(reflect.ValueOf(&s).Convert(fv.Type()))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package common_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aluka-7/common"
+	"github.com/rs/zerolog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type maskedContact struct {
+	Mobile string  `json:"mobile" mask:"mobile"`
+	Email  string  `json:"email" mask:"email"`
+	IdCard *string `json:"idCard" mask:"idcard"`
+	Secret string  `json:"secret" mask:"all"`
+	Plate  string  `json:"plate" mask:"plate"`
+}
+
+type maskedUser struct {
+	Name     string           `json:"name"`
+	Contact  maskedContact    `json:"contact"`
+	Contacts []*maskedContact `json:"contacts"`
+}
+
+// maskedComment 自引用字段在脱敏字段之前
+type maskedComment struct {
+	Replies []maskedComment `json:"replies"`
+	Mobile  string          `json:"mobile" mask:"mobile"`
+}
+
+type maskedNode struct {
+	Next   *maskedNode `json:"next"`
+	Mobile string      `json:"mobile" mask:"mobile"`
+}
+
+func init() {
+	common.RegisterMasker("plate", func(s string) string {
+		if len(s) < 2 {
+			return s
+		}
+		return s[:len(s)-2] + "**"
+	})
+}
+
+func TestMask(t *testing.T) {
+	Convey("Test Mask", t, func() {
+		idCard := "110101199003071234"
+		user := maskedUser{
+			Name: "张三",
+			Contact: maskedContact{
+				Mobile: "13600009714",
+				Email:  "zhangsan@example.com",
+				IdCard: &idCard,
+				Secret: "p@ss",
+				Plate:  "京A12345",
+			},
+		}
+		user.Contacts = []*maskedContact{&user.Contact}
+
+		Convey("Test tags are applied recursively", func() {
+			m := common.Mask(common.DtoResult{Result: common.OK(), Data: user}).(common.DtoResult).Data.(maskedUser)
+			So(m.Name, ShouldEqual, "张三")
+			So(m.Contact.Mobile, ShouldEqual, "136****9714")
+			So(m.Contact.Email, ShouldEqual, "z***@example.com")
+			So(*m.Contact.IdCard, ShouldEqual, "110***********1234")
+			So(m.Contact.Secret, ShouldEqual, "****")
+			So(m.Contact.Plate, ShouldEqual, "京A123**")
+			So(m.Contacts[0].Mobile, ShouldEqual, "136****9714")
+		})
+		Convey("Test original value is untouched", func() {
+			_ = common.Json(user, false)
+			So(user.Contact.Mobile, ShouldEqual, "13600009714")
+			So(idCard, ShouldEqual, "110101199003071234")
+			So(user.Contacts[0].Email, ShouldEqual, "zhangsan@example.com")
+		})
+		Convey("Test Json masks and Trusted opts out", func() {
+			So(common.Json(user, false), ShouldContainSubstring, `"mobile":"136****9714"`)
+			So(common.Json(common.Trusted(user), false), ShouldContainSubstring, `"mobile":"13600009714"`)
+		})
+		Convey("Test values without tags are returned as is", func() {
+			v := map[string]int{"a": 1}
+			So(common.Mask(v), ShouldEqual, v)
+		})
+		Convey("Test zerolog output is masked", func() {
+			var buf bytes.Buffer
+			common.EnableLogMasking()
+			logger := zerolog.New(&buf)
+			logger.Info().Interface("user", user).Send()
+			So(buf.String(), ShouldContainSubstring, `"mobile":"136****9714"`)
+			So(buf.String(), ShouldNotContainSubstring, "13600009714")
+		})
+		Convey("Test self referencing types are masked concurrently", func() {
+			c := maskedComment{Mobile: "13600009714", Replies: []maskedComment{{Mobile: "13800001234"}}}
+			out := make(chan string, 8)
+			for i := 0; i < cap(out); i++ {
+				go func() { out <- common.Json(c, false) }()
+			}
+			for i := 0; i < cap(out); i++ {
+				s := <-out
+				So(s, ShouldContainSubstring, `"mobile":"138****1234"`)
+				So(s, ShouldNotContainSubstring, "13800001234")
+				So(s, ShouldNotContainSubstring, "13600009714")
+			}
+		})
+		Convey("Test cyclic values fail instead of overflowing", func() {
+			n := &maskedNode{Mobile: "13600009714"}
+			n.Next = n
+			_, err := common.MarshalJson(n, false)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("Test callback data is not masked", func() {
+			So(common.NewCallBack(1, user).Data, ShouldContainSubstring, "13600009714")
+		})
+		Convey("Test response writers mask unless trusted", func() {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			So(common.WriteOK(rec, req, user), ShouldBeNil)
+			So(rec.Body.String(), ShouldNotContainSubstring, "13600009714")
+
+			rec = httptest.NewRecorder()
+			So(common.WriteOK(rec, req.WithContext(common.WithTrusted(req.Context())), user), ShouldBeNil)
+			So(strings.Count(rec.Body.String(), "13600009714"), ShouldEqual, 2)
+		})
+	})
+}
@@ -0,0 +1,191 @@
+// Package openapi 根据接口声明的请求和返回类型生成OpenAPI 3.1文档。
+//
+// 返回结果使用common包的泛型结果类型声明，如common.DtoResultOf[User]、common.PageResultOf[[]User]，
+// 生成的Schema中data按具体类型描述；Result、Page、Query等公共类型作为components复用。
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aluka-7/common"
+)
+
+// Version 生成文档使用的OpenAPI版本
+const Version = "3.1.0"
+
+// Document OpenAPI文档
+type Document struct {
+	OpenAPI    string                        `json:"openapi"`
+	Info       Info                          `json:"info"`
+	Paths      map[string]map[string]*PathOp `json:"paths"`
+	Components Components                    `json:"components"`
+}
+
+// Info 文档的基本信息
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components 可复用的Schema
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathOp 生成的OpenAPI Operation对象
+type PathOp struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter 路径和查询参数
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody 请求体
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response 返回结果
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType 某种媒体类型的内容
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Operation 接口声明，Request和Response使用类型的零值，如Request: SignupRequest{}、Response: common.DtoResultOf[User]{}
+type Operation struct {
+	Method   string      // HTTP方法
+	Path     string      // 路径，路径参数使用{name}，如/users/{id}
+	ID       string      // operationId，为空时不生成
+	Summary  string      // 接口说明
+	Tags     []string    // 接口分组
+	Request  interface{} // 请求体类型，为nil表示没有请求体
+	Response interface{} // 成功时的返回结果类型，为nil时使用common.Result
+	Fields   bool        // 是否支持通过fields参数进行返回字段投影
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Registry 接口声明的注册表，可以作为http.Handler提供JSON格式的OpenAPI文档
+type Registry struct {
+	info Info
+	mu   sync.RWMutex
+	ops  []Operation
+	spec []byte // 缓存的文档，注册新的接口后重新生成
+	gen  int    // 注册的次数，用于判断生成期间是否有新的注册
+}
+
+// NewRegistry 创建接口注册表
+// @param           title       文档标题
+// @param           version     接口版本
+func NewRegistry(title, version string) *Registry {
+	return &Registry{info: Info{Title: title, Version: version}}
+}
+
+// Register 声明接口的请求和返回类型，同一方法和路径重复声明时后者覆盖前者
+func (r *Registry) Register(op Operation) {
+	op.Method = strings.ToUpper(op.Method)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, old := range r.ops {
+		if old.Method == op.Method && old.Path == op.Path {
+			r.ops[i] = op
+			r.spec, r.gen = nil, r.gen+1
+			return
+		}
+	}
+	r.ops = append(r.ops, op)
+	r.spec, r.gen = nil, r.gen+1
+}
+
+// Handle 声明接口并原样返回处理器，便于在注册路由时同时声明，如mux.Handle("/users", reg.Handle(op, h))
+func (r *Registry) Handle(op Operation, h http.Handler) http.Handler {
+	r.Register(op)
+	return h
+}
+
+// Document 生成完整的OpenAPI文档，公共的Result、Page和Query总是包含在components中
+func (r *Registry) Document() *Document {
+	r.mu.RLock()
+	ops := append([]Operation(nil), r.ops...)
+	r.mu.RUnlock()
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].Path < ops[j].Path })
+
+	g := NewGenerator()
+	g.SchemaOf(common.Result{})
+	g.SchemaOf(common.Page{})
+	g.SchemaOf(common.Query{})
+	problem := g.SchemaOf(common.Problem{})
+	failure := &Response{Description: "失败的结果", Content: map[string]MediaType{
+		common.MIMEJSON:    {Schema: g.SchemaOf(common.Result{})},
+		common.MIMEProblem: {Schema: problem},
+	}}
+	doc := &Document{OpenAPI: Version, Info: r.info, Paths: make(map[string]map[string]*PathOp)}
+	for _, op := range ops {
+		po := &PathOp{OperationID: op.ID, Summary: op.Summary, Tags: op.Tags, Responses: map[string]*Response{"default": failure}}
+		for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+			po.Parameters = append(po.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		if op.Fields {
+			po.Parameters = append(po.Parameters, Parameter{
+				Name: common.FieldsParam, In: "query", Description: "返回字段，逗号分隔，下级字段使用.分隔", Schema: &Schema{Type: "string"},
+			})
+		}
+		if op.Request != nil {
+			po.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{common.MIMEJSON: {Schema: g.SchemaOf(op.Request)}}}
+		}
+		resp := op.Response
+		if resp == nil {
+			resp = common.Result{}
+		}
+		po.Responses["200"] = &Response{Description: "成功的结果", Content: map[string]MediaType{common.MIMEJSON: {Schema: g.SchemaOf(resp)}}}
+		if doc.Paths[op.Path] == nil {
+			doc.Paths[op.Path] = make(map[string]*PathOp)
+		}
+		doc.Paths[op.Path][strings.ToLower(op.Method)] = po
+	}
+	doc.Components.Schemas = g.Components()
+	return doc
+}
+
+// ServeHTTP 返回JSON格式的OpenAPI文档
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	spec, gen := r.spec, r.gen
+	r.mu.RUnlock()
+	if spec == nil {
+		var err error
+		if spec, err = json.MarshalIndent(r.Document(), "", "  "); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		r.mu.Lock()
+		if r.gen == gen {
+			r.spec = spec
+		}
+		r.mu.Unlock()
+	}
+	w.Header().Set("Content-Type", common.MIMEJSON)
+	_, _ = w.Write(spec)
+}
@@ -0,0 +1,124 @@
+package openapi
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var update = flag.Bool("update", false, "更新testdata中的golden文件")
+
+type user struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Mobile    string    `json:"mobile" mask:"mobile"`
+	Nickname  *string   `json:"nickname"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	password  string
+}
+
+type signupRequest struct {
+	Name   string `json:"name" validate:"required,max=20"`
+	Email  string `json:"email" validate:"omitempty,email"`
+	Gender string `json:"gender" validate:"oneof=male female"`
+	Age    int    `json:"age" validate:"min=18"`
+}
+
+func newTestRegistry() *Registry {
+	reg := NewRegistry("用户服务", "1.0.0")
+	reg.Register(Operation{Method: http.MethodPost, Path: "/users", ID: "signup", Request: signupRequest{}, Response: common.DtoResultOf[user]{}})
+	reg.Register(Operation{Method: http.MethodGet, Path: "/users/{id}", Response: common.DtoResultOf[user]{}, Fields: true})
+	reg.Register(Operation{Method: http.MethodPost, Path: "/users/search", Request: common.Query{}, Response: common.PageResultOf[[]user]{}})
+	reg.Register(Operation{Method: http.MethodDelete, Path: "/users/{id}"})
+	return reg
+}
+
+func TestSchema(t *testing.T) {
+	Convey("Test Schema Generation", t, func() {
+		g := NewGenerator()
+		Convey("Test envelopes are specialised per payload", func() {
+			So(g.SchemaOf(common.DtoResultOf[user]{}), ShouldResemble, Ref("DtoResultOf_user"))
+			So(g.SchemaOf(common.PageResultOf[[]user]{}), ShouldResemble, Ref("PageResultOf_Arrayuser"))
+			dto := g.Components()["DtoResultOf_user"]
+			So(dto.Properties["code"].Type, ShouldEqual, "integer")
+			So(dto.Properties["data"], ShouldResemble, Ref("user"))
+			page := g.Components()["PageResultOf_Arrayuser"]
+			So(page.Properties["pagination"], ShouldResemble, Ref("Page"))
+			So(page.Properties["data"], ShouldResemble, &Schema{Type: "array", Items: Ref("user")})
+			So(g.Components()["user"].Properties, ShouldNotContainKey, "password")
+			So(g.Components()["user"].Properties["createdAt"].Format, ShouldEqual, "date-time")
+		})
+		Convey("Test untyped envelope data", func() {
+			g.SchemaOf(common.DtoResult{})
+			So(g.Components()["DtoResultOf_interface"].Properties["data"], ShouldResemble, &Schema{})
+		})
+		Convey("Test pointers are nullable", func() {
+			type profile struct {
+				Avatar   *string          `json:"avatar"`
+				Manager  *user            `json:"manager"`
+				Required *string          `json:"required" validate:"required"`
+				Friends  []*user          `json:"friends"`
+				Extra    map[string]*int  `json:"extra"`
+				Any      *json.RawMessage `json:"any"`
+			}
+			g.SchemaOf(profile{})
+			s := g.Components()["profile"]
+			null := &Schema{Type: "null"}
+			So(s.Properties["avatar"], ShouldResemble, &Schema{OneOf: []*Schema{{Type: "string"}, null}})
+			So(s.Properties["manager"], ShouldResemble, &Schema{OneOf: []*Schema{Ref("user"), null}})
+			So(s.Properties["required"], ShouldResemble, &Schema{Type: "string"})
+			So(s.Properties["friends"].Items, ShouldResemble, &Schema{OneOf: []*Schema{Ref("user"), null}})
+			So(s.Properties["extra"].AdditionalProperties.OneOf, ShouldHaveLength, 2)
+			So(s.Properties["any"], ShouldResemble, &Schema{})
+			So(g.Components()["user"].Properties["nickname"].OneOf, ShouldHaveLength, 2)
+		})
+		Convey("Test validate rules", func() {
+			g.SchemaOf(signupRequest{})
+			s := g.Components()["signupRequest"]
+			So(s.Required, ShouldResemble, []string{"name"})
+			So(*s.Properties["name"].MaxLength, ShouldEqual, 20)
+			So(s.Properties["email"].Format, ShouldEqual, "email")
+			So(s.Properties["gender"].Enum, ShouldResemble, []interface{}{"male", "female"})
+			So(*s.Properties["age"].Minimum, ShouldEqual, 18)
+		})
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	Convey("Test Registry", t, func() {
+		reg := newTestRegistry()
+		rec := httptest.NewRecorder()
+		reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		So(rec.Code, ShouldEqual, http.StatusOK)
+		var doc Document
+		So(json.Unmarshal(rec.Body.Bytes(), &doc), ShouldBeNil)
+		So(doc.OpenAPI, ShouldEqual, Version)
+		So(doc.Paths["/users/{id}"]["get"].Parameters, ShouldHaveLength, 2)
+		So(doc.Paths["/users/{id}"]["delete"].Responses["200"].Content[common.MIMEJSON].Schema, ShouldResemble, Ref("Result"))
+		So(doc.Components.Schemas, ShouldContainKey, "Query")
+
+		path := filepath.Join("testdata", "openapi.golden")
+		if *update {
+			So(os.WriteFile(path, rec.Body.Bytes(), 0644), ShouldBeNil)
+		}
+		want, err := os.ReadFile(path)
+		So(err, ShouldBeNil)
+		So(rec.Body.String(), ShouldEqual, string(want))
+
+		Convey("Test new registrations refresh the spec", func() {
+			reg.Register(Operation{Method: http.MethodGet, Path: "/health"})
+			rec := httptest.NewRecorder()
+			reg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+			So(rec.Body.String(), ShouldContainSubstring, `"/health"`)
+		})
+	})
+}
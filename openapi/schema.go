@@ -0,0 +1,269 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema OpenAPI 3.1(JSON Schema 2020-12)的Schema对象，只包含生成器用到的关键字
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Ref 返回引用components中给定名称的Schema
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	pkgPathPattern    = regexp.MustCompile(`[\w.\-]+/`) // 泛型参数中的包路径，如github.com/aluka-7/
+	pkgNamePattern    = regexp.MustCompile(`\b\w+\.`)   // 泛型参数中的包名，如common.
+	invalidNameChars  = regexp.MustCompile(`[^\w.\-]+`) // components名称只能包含字母、数字和._-
+)
+
+// Generator 通过反射生成Schema，具名结构体放入components并以$ref引用，同一类型只生成一次
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// NewGenerator 创建Schema生成器
+func NewGenerator() *Generator {
+	return &Generator{schemas: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// Components 返回已生成的具名Schema，键为components/schemas中的名称
+func (g *Generator) Components() map[string]*Schema {
+	return g.schemas
+}
+
+// SchemaOf 返回v的类型对应的Schema，v为nil时返回nil
+func (g *Generator) SchemaOf(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	return g.Schema(reflect.TypeOf(v))
+}
+
+// Schema 返回类型对应的Schema，字段名使用JSON名称，validate标签转换为required、minimum、maxLength、enum等约束。
+// 泛型类型按类型参数生成不同的名称，如DtoResultOf[[]User]生成DtoResultOf_ArrayUser，使data按接口的返回类型描述。
+func (g *Generator) Schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		return &Schema{} // 自定义序列化的类型无法推导结构
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &Schema{Type: "array", Items: g.nullable(t.Elem(), g.Schema(t.Elem()))}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.nullable(t.Elem(), g.Schema(t.Elem()))}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		if name, ok := g.names[t]; ok {
+			return Ref(name)
+		}
+		name := g.uniqueName(t)
+		g.names[t] = name
+		g.schemas[name] = nil // 占位，避免递归类型无限生成
+		g.schemas[name] = g.object(t)
+		return Ref(name)
+	}
+	return &Schema{} // interface{}等任意类型
+}
+
+// typeName 生成components中的名称，泛型参数去掉包路径，[]转换为Array
+func typeName(t reflect.Type) string {
+	name := t.Name()
+	i := strings.IndexByte(name, '[')
+	if i < 0 {
+		return name
+	}
+	args := pkgNamePattern.ReplaceAllString(pkgPathPattern.ReplaceAllString(name[i:], ""), "")
+	args = strings.NewReplacer("[]", "Array", "map[", "Map", ",", "_", "*", "").Replace(args)
+	return name[:i] + "_" + invalidNameChars.ReplaceAllString(args, "")
+}
+
+// uniqueName 不同包中同名的类型在名称后追加序号
+func (g *Generator) uniqueName(t reflect.Type) string {
+	base := typeName(t)
+	name := base
+	for n := 2; ; n++ {
+		if _, ok := g.schemas[name]; !ok {
+			return name
+		}
+		name = base + strconv.Itoa(n)
+	}
+}
+
+func (g *Generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, s)
+	return s
+}
+
+// fields 按encoding/json的规则生成结构体字段，匿名结构体的字段提升到上级
+func (g *Generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.fields(ft, s)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := g.Schema(f.Type)
+		required := hasRule(f.Tag.Get("validate"), "required")
+		if fs.Ref == "" {
+			required = applyRules(fs, f.Tag.Get("validate"))
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		} else {
+			fs = g.nullable(f.Type, fs)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// nullable 指针类型的值可以为null，按OpenAPI 3.1的写法使用oneOf允许null，非指针类型和任意类型原样返回
+func (g *Generator) nullable(t reflect.Type, s *Schema) *Schema {
+	if t.Kind() != reflect.Ptr || reflect.DeepEqual(*s, Schema{}) {
+		return s
+	}
+	return &Schema{OneOf: []*Schema{s, {Type: "null"}}}
+}
+
+func hasRule(tag, rule string) bool {
+	for _, part := range strings.Split(tag, ",") {
+		if strings.TrimSpace(part) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules 将common.Validate使用的validate标签转换为Schema约束，返回字段是否必填
+func applyRules(s *Schema, tag string) (required bool) {
+	for _, part := range strings.Split(tag, ",") {
+		name, param := strings.TrimSpace(part), ""
+		if j := strings.IndexByte(name, '='); j >= 0 {
+			name, param = name[:j], name[j+1:]
+		}
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "oneof":
+			for _, option := range strings.Fields(param) {
+				if s.Type == "integer" {
+					if n, err := strconv.ParseInt(option, 10, 64); err == nil {
+						s.Enum = append(s.Enum, n)
+						continue
+					}
+				}
+				s.Enum = append(s.Enum, option)
+			}
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			if name == "len" || name == "min" {
+				setBound(s, n, true)
+			}
+			if name == "len" || name == "max" {
+				setBound(s, n, false)
+			}
+		}
+	}
+	return
+}
+
+func setBound(s *Schema, n float64, lower bool) {
+	i := int(n)
+	switch s.Type {
+	case "integer", "number":
+		if lower {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	case "string":
+		if lower {
+			s.MinLength = &i
+		} else {
+			s.MaxLength = &i
+		}
+	case "array":
+		if lower {
+			s.MinItems = &i
+		} else {
+			s.MaxItems = &i
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package common
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	OptHeader      = "X-Operation-Id" // 传递操作编号的HTTP头
+	OptMetadataKey = "x-operation-id" // 传递操作编号的gRPC metadata键
+)
+
+// crockford ULID使用的Crockford Base32字符表，按字典序排列，保证编号可排序
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var optGen struct {
+	sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
+}
+
+// NewOpt 生成ULID格式的操作编号，由48位毫秒时间戳和80位随机数组成，共26个字符。
+// 编号按生成时间的字典序递增，同一毫秒内生成的编号在随机部分上递增，保证单个进程内严格有序。
+func NewOpt() string {
+	var id [16]byte
+	optGen.Lock()
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	if ms > optGen.lastMs || !incEntropy() {
+		if ms <= optGen.lastMs {
+			ms = optGen.lastMs + 1
+		}
+		optGen.lastMs = ms
+		_, _ = rand.Read(optGen.entropy[:])
+	}
+	binary.BigEndian.PutUint16(id[:2], uint16(optGen.lastMs>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(optGen.lastMs))
+	copy(id[6:], optGen.entropy[:])
+	optGen.Unlock()
+
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// incEntropy 将随机部分加一，溢出时返回false
+func incEntropy() bool {
+	for i := len(optGen.entropy) - 1; i >= 0; i-- {
+		optGen.entropy[i]++
+		if optGen.entropy[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validOpt 校验调用方传入的操作编号，只接受64个字符以内的字母、数字和-_.，避免日志注入
+func validOpt(opt string) bool {
+	if opt == "" || len(opt) > 64 {
+		return false
+	}
+	for _, c := range opt {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// OptHandler HTTP中间件，从请求头读取操作编号，没有或不合法时生成新编号，保存到请求上下文并写入响应头
+func OptHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opt := r.Header.Get(OptHeader)
+		if !validOpt(opt) {
+			opt = NewOpt()
+		}
+		w.Header().Set(OptHeader, opt)
+		next.ServeHTTP(w, r.WithContext(WithOpt(r.Context(), opt)))
+	})
+}
+
+// optFromIncoming 从gRPC请求的metadata读取操作编号，没有或不合法时生成新编号
+func optFromIncoming(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vs := md.Get(OptMetadataKey); len(vs) > 0 && validOpt(vs[0]) {
+			return vs[0]
+		}
+	}
+	return NewOpt()
+}
+
+// OptUnaryServerInterceptor gRPC服务端一元拦截器，读取或生成操作编号并保存到上下文，同时写入响应header
+func OptUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		opt := optFromIncoming(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(OptMetadataKey, opt))
+		return handler(WithOpt(ctx, opt), req)
+	}
+}
+
+// OptStreamServerInterceptor gRPC服务端流拦截器，读取或生成操作编号并保存到流的上下文，同时写入响应header
+func OptStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		opt := optFromIncoming(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(OptMetadataKey, opt))
+		return handler(srv, &optServerStream{ServerStream: ss, ctx: WithOpt(ss.Context(), opt)})
+	}
+}
+
+type optServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *optServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// withOutgoingOpt 将上下文中的操作编号写入发往下游的metadata
+func withOutgoingOpt(ctx context.Context) context.Context {
+	if opt := OptFromContext(ctx); opt != "" {
+		return metadata.AppendToOutgoingContext(ctx, OptMetadataKey, opt)
+	}
+	return ctx
+}
+
+// OptUnaryClientInterceptor gRPC客户端一元拦截器，将上下文中的操作编号传递给下游服务
+func OptUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(withOutgoingOpt(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// OptStreamClientInterceptor gRPC客户端流拦截器，将上下文中的操作编号传递给下游服务
+func OptStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(withOutgoingOpt(ctx), desc, cc, method, opts...)
+	}
+}
+
+// OptHook zerolog的钩子，为带有上下文的日志添加opt字段，使用方式：
+//
+//	log.Logger = log.Hook(common.OptHook{})
+//	log.Info().Ctx(r.Context()).Msg("...")
+//
+// 钩子只能从事件的上下文中读取操作编号，没有调用Ctx的日志不会带有opt字段，
+// 无法逐条传递上下文时使用OptLogger创建带有opt字段的logger。
+type OptHook struct{}
+
+func (OptHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if opt := OptFromContext(e.GetCtx()); opt != "" {
+		e.Str("opt", opt)
+	}
+}
+
+// OptLogger 返回附带ctx中操作编号的logger，之后通过它记录的日志不需要再调用Ctx，ctx中没有操作编号时返回l本身
+func OptLogger(ctx context.Context, l zerolog.Logger) zerolog.Logger {
+	if opt := OptFromContext(ctx); opt != "" {
+		return l.With().Str("opt", opt).Logger()
+	}
+	return l
+}
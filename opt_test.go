@@ -0,0 +1,80 @@
+package common_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/aluka-7/common"
+	"github.com/rs/zerolog"
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestOpt(t *testing.T) {
+	Convey("Test Operation Id", t, func() {
+		Convey("Test generated ids are sortable and unique", func() {
+			ids := make([]string, 1000)
+			for i := range ids {
+				ids[i] = common.NewOpt()
+			}
+			So(len(ids[0]), ShouldEqual, 26)
+			So(sort.StringsAreSorted(ids), ShouldBeTrue)
+			seen := make(map[string]bool)
+			for _, id := range ids {
+				So(seen[id], ShouldBeFalse)
+				seen[id] = true
+			}
+		})
+		Convey("Test http middleware", func() {
+			var opt string
+			h := common.OptHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				opt = common.OptFromContext(r.Context())
+			}))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(common.OptHeader, "client-op-1")
+			h.ServeHTTP(rec, req)
+			So(opt, ShouldEqual, "client-op-1")
+			So(rec.Header().Get(common.OptHeader), ShouldEqual, "client-op-1")
+
+			req.Header.Set(common.OptHeader, "bad\nvalue")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			So(len(opt), ShouldEqual, 26)
+		})
+		Convey("Test grpc propagation", func() {
+			var outgoing metadata.MD
+			client := common.OptUnaryClientInterceptor()
+			_ = client(common.WithOpt(context.Background(), "op-1"), "/user.User/Get", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				outgoing, _ = metadata.FromOutgoingContext(ctx)
+				return nil
+			})
+			So(outgoing.Get(common.OptMetadataKey), ShouldResemble, []string{"op-1"})
+
+			var opt string
+			server := common.OptUnaryServerInterceptor()
+			ctx := metadata.NewIncomingContext(context.Background(), outgoing)
+			_, _ = server(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				opt = common.OptFromContext(ctx)
+				return nil, nil
+			})
+			So(opt, ShouldEqual, "op-1")
+		})
+		Convey("Test zerolog hook", func() {
+			var buf bytes.Buffer
+			logger := zerolog.New(&buf).Hook(common.OptHook{})
+			logger.Info().Ctx(common.WithOpt(context.Background(), "op-1")).Msg("hello")
+			So(buf.String(), ShouldContainSubstring, `"opt":"op-1"`)
+		})
+		Convey("Test opt logger without event context", func() {
+			var buf bytes.Buffer
+			logger := common.OptLogger(common.WithOpt(context.Background(), "op-2"), zerolog.New(&buf))
+			logger.Info().Msg("hello")
+			So(buf.String(), ShouldContainSubstring, `"opt":"op-2"`)
+		})
+	})
+}
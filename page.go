@@ -1,34 +1,78 @@
 package common
 
+import "fmt"
+
+const DefaultPageSize = 20 // 默认的每页大小
+
+// MaxPageSize NewPaginationStrict允许的最大每页大小，避免调用方一次查询过多记录
+var MaxPageSize = 1000
+
+// Pagination 分页器，页码从1开始，设置总记录数后页码会被限制在[1, TotalPages]范围内
 type Pagination struct {
-	pageNo       int // 当前页码
-	PageSize     int // 每页大小
-	TotalPages   int // 总页数
-	TotalRecords int // 总记录数
+	PageNo       int  `json:"page"`         // 当前页码
+	PageSize     int  `json:"pageSize"`     // 每页大小
+	TotalPages   int  `json:"totalPages"`   // 总页数
+	TotalRecords int  `json:"totalRecords"` // 总记录数
+	totalsKnown  bool // 是否已经设置了总记录数，设置后才能确定页码的上限
 }
 
 func DefaultPagination() *Pagination {
-	return NewPagination(1, 20, 0)
+	return NewPagination(1, DefaultPageSize, 0)
 }
-func NewPagination(pageNo, pageSize, totalPages int) *Pagination {
-	return &Pagination{
-		pageNo:     pageNo,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+
+// NewPagination 创建分页器，页码小于1时使用1，页大小小于1时使用DefaultPageSize，总记录数小于0时使用0。
+// 总记录数大于0时视为已知，页码会被限制在有效范围内；为0时一般是查询前创建，需要在查询后调用SetTotalRecord。
+func NewPagination(pageNo, pageSize, totalRecords int) *Pagination {
+	if pageNo < 1 {
+		pageNo = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	p := &Pagination{PageNo: pageNo, PageSize: pageSize}
+	if totalRecords > 0 {
+		p.SetTotalRecord(totalRecords)
+	} else {
+		p.computeTotalPages()
+	}
+	return p
+}
+
+// NewPaginationStrict 校验参数后创建分页器，页码小于1、页大小不在[1, MaxPageSize]范围内或总记录数小于0时
+// 返回以ErrCodeBadRequest包装的错误，用于校验调用方传入的分页参数。
+func NewPaginationStrict(pageNo, pageSize, totalRecords int) (*Pagination, error) {
+	switch {
+	case pageNo < 1:
+		return nil, WrapError(fmt.Errorf("页码[%d]必须大于0", pageNo), ErrCodeBadRequest)
+	case pageSize < 1 || pageSize > MaxPageSize:
+		return nil, WrapError(fmt.Errorf("每页大小[%d]必须在1到%d之间", pageSize, MaxPageSize), ErrCodeBadRequest)
+	case totalRecords < 0:
+		return nil, WrapError(fmt.Errorf("总记录数[%d]不能小于0", totalRecords), ErrCodeBadRequest)
 	}
+	return NewPagination(pageNo, pageSize, totalRecords), nil
 }
 
-// SetPageNumber 设置当前分页的页码信息，必须大于0，否则设置无效。默认是1
+// PageNumber 获取当前页码
+func (p *Pagination) PageNumber() int {
+	return p.PageNo
+}
+
+// SetPageNumber 设置当前分页的页码信息，必须大于0，否则设置无效。已设置总记录数时超过总页数的页码设置为最后一页
 func (p *Pagination) SetPageNumber(pageNo int) {
-	if p.pageNo <= 0 {
+	if pageNo <= 0 {
 		return
 	}
-	p.pageNo = pageNo
+	p.PageNo = pageNo
+	p.clampPageNo()
 }
 
-// SetTotalRecord 设置当前分页信息的总记录数
+// SetTotalRecord 设置当前分页信息的总记录数，小于0时使用0，设置后页码被限制在[1, TotalPages]范围内
 func (p *Pagination) SetTotalRecord(totalRecords int) {
+	if totalRecords < 0 {
+		totalRecords = 0
+	}
 	p.TotalRecords = totalRecords
+	p.totalsKnown = true
 	p.computeTotalPages()
 }
 
@@ -41,53 +85,67 @@ func (p *Pagination) SetPageSize(pageSize int) {
 	p.computeTotalPages()
 }
 
-//computeTotalPages 根据总记录数和页大小计算总页数，在每次设置完总记录数和页大小后都会自动 进行计算，即方法SetPageSize和SetTotalRecord被调用后会自动调用该方法进行总页数的计算。
+// size 返回有效的页大小，直接修改PageSize为非正数时使用DefaultPageSize，避免除0
+func (p *Pagination) size() int {
+	if p.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	return p.PageSize
+}
+
+// computeTotalPages 根据总记录数和页大小计算总页数，在每次设置完总记录数和页大小后都会自动进行计算，
+// 即方法SetPageSize和SetTotalRecord被调用后会自动调用该方法进行总页数的计算。
 func (p *Pagination) computeTotalPages() {
-	p.TotalPages = p.TotalRecords / p.PageSize
-	if (p.TotalRecords % p.PageSize) != 0 {
-		p.TotalPages += 1
+	p.TotalPages = p.TotalPage()
+	p.clampPageNo()
+}
+
+// clampPageNo 将页码限制在[1, TotalPages]范围内，总记录数未知时只限制下限
+func (p *Pagination) clampPageNo() {
+	if p.totalsKnown && p.PageNo > p.TotalPages {
+		p.PageNo = p.TotalPages
 	}
-	if p.TotalPages <= 0 {
-		p.TotalPages = 1
+	if p.PageNo < 1 {
+		p.PageNo = 1
 	}
 }
 
 // Limit 获取mysql分页值（limit,offset）
 func (p *Pagination) Limit() (limit int, offset int) {
 	pageNo := 0
-	if p.pageNo > 0 {
-		pageNo = p.pageNo - 1
+	if p.PageNo > 0 {
+		pageNo = p.PageNo - 1
 	}
-	return p.PageSize, pageNo * p.PageSize
+	return p.size(), pageNo * p.size()
 }
 
 // IsFirst 如果当前页面是第一页，则返回true.
 func (p *Pagination) IsFirst() bool {
-	return p.pageNo == 1
+	return p.PageNo == 1
 }
 
 // HasPrevious 如果存在相对于当前页面的上一页，则返回true.
 func (p *Pagination) HasPrevious() bool {
-	return p.pageNo > 1
+	return p.PageNo > 1
 }
 
 func (p *Pagination) Previous() int {
 	if !p.HasPrevious() {
-		return p.pageNo
+		return p.PageNo
 	}
-	return p.pageNo - 1
+	return p.PageNo - 1
 }
 
 // HasNext 如果存在相对于当前页面的下一页，则返回true.
 func (p *Pagination) HasNext() bool {
-	return p.TotalRecords > p.pageNo*p.PageSize
+	return p.TotalRecords > p.PageNo*p.size()
 }
 
 func (p *Pagination) Next() int {
 	if !p.HasNext() {
-		return p.pageNo
+		return p.PageNo
 	}
-	return p.pageNo + 1
+	return p.PageNo + 1
 }
 
 // IsLast 如果当前页面是最后一页，则返回true.
@@ -95,7 +153,7 @@ func (p *Pagination) IsLast() bool {
 	if p.TotalRecords == 0 {
 		return true
 	}
-	return p.TotalRecords > (p.pageNo-1)*p.PageSize && !p.HasNext()
+	return p.TotalRecords > (p.PageNo-1)*p.size() && !p.HasNext()
 }
 
 // Total returns number of total rows.
@@ -103,15 +161,15 @@ func (p *Pagination) Total() int {
 	return p.TotalRecords
 }
 
-// TotalPage 返回总页数.
+// TotalPage 返回总页数，没有记录时为1.
 func (p *Pagination) TotalPage() int {
-	if p.TotalRecords == 0 {
+	if p.TotalRecords <= 0 {
 		return 1
 	}
-	if p.TotalRecords%p.PageSize == 0 {
-		return p.TotalRecords / p.PageSize
+	if p.TotalRecords%p.size() == 0 {
+		return p.TotalRecords / p.size()
 	}
-	return p.TotalRecords/p.PageSize + 1
+	return p.TotalRecords/p.size() + 1
 }
 
 type PaginationList struct {
@@ -153,7 +211,7 @@ func (p *Pagination) Pages() []*Paginator {
 	if p.TotalPage() <= p.TotalPages {
 		pages := make([]*Paginator, p.TotalPage())
 		for i := range pages {
-			pages[i] = &Paginator{i + 1, i+1 == p.TotalPages}
+			pages[i] = &Paginator{i + 1, i+1 == p.PageNo}
 		}
 		return pages
 	}
@@ -165,24 +223,24 @@ func (p *Pagination) Pages() []*Paginator {
 
 	// 检查更多的上一页和下一页。
 	previousNum := getMiddleIdx(p.TotalPages) - 1
-	if previousNum > p.pageNo-1 {
-		previousNum -= previousNum - (p.pageNo - 1)
+	if previousNum > p.PageNo-1 {
+		previousNum -= previousNum - (p.PageNo - 1)
 	}
 	nextNum := p.TotalPages - previousNum - 1
-	if p.pageNo+nextNum > p.TotalPage() {
-		delta := nextNum - (p.TotalPage() - p.pageNo)
+	if p.PageNo+nextNum > p.TotalPage() {
+		delta := nextNum - (p.TotalPage() - p.PageNo)
 		nextNum -= delta
 		previousNum += delta
 	}
 
-	offsetVal := p.pageNo - previousNum
+	offsetVal := p.PageNo - previousNum
 	if offsetVal > 1 {
 		numPages++
 		maxIdx++
 		offsetIdx = 1
 	}
 
-	if p.pageNo+nextNum < p.TotalPage() {
+	if p.PageNo+nextNum < p.TotalPage() {
 		numPages++
 		hasMoreNext = true
 	}
@@ -203,11 +261,11 @@ func (p *Pagination) Pages() []*Paginator {
 		pages[offsetIdx+i] = &Paginator{i + offsetVal, false}
 	}
 
-	pages[offsetIdx+previousNum] = &Paginator{p.pageNo, true}
+	pages[offsetIdx+previousNum] = &Paginator{p.PageNo, true}
 
 	// 检查下一页.
 	for i := 1; i <= nextNum; i++ {
-		pages[offsetIdx+previousNum+i] = &Paginator{p.pageNo + i, false}
+		pages[offsetIdx+previousNum+i] = &Paginator{p.PageNo + i, false}
 	}
 	return pages
 }
@@ -0,0 +1,112 @@
+package common_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"testing/quick"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// checkInvariants 校验已设置总记录数的分页器满足的不变式
+func checkInvariants(p *common.Pagination) bool {
+	limit, offset := p.Limit()
+	return p.PageSize >= 1 &&
+		p.TotalRecords >= 0 &&
+		p.TotalPages >= 1 &&
+		p.TotalPages == p.TotalPage() &&
+		p.PageNo >= 1 && p.PageNo <= p.TotalPages &&
+		(p.TotalRecords == 0 && p.TotalPages == 1 ||
+			(p.TotalPages-1)*p.PageSize < p.TotalRecords && p.TotalRecords <= p.TotalPages*p.PageSize) &&
+		limit == p.PageSize && offset == (p.PageNo-1)*p.PageSize &&
+		p.HasNext() == (p.PageNo < p.TotalPages) &&
+		p.HasPrevious() == (p.PageNo > 1) &&
+		p.IsLast() == (p.PageNo == p.TotalPages)
+}
+
+func TestPaginationProperties(t *testing.T) {
+	Convey("Test Pagination Properties", t, func() {
+		Convey("Test invariants hold for any input", func() {
+			err := quick.Check(func(pageNo, pageSize int16, records int32) bool {
+				p := common.NewPagination(int(pageNo), int(pageSize), 0)
+				p.SetTotalRecord(int(records))
+				return checkInvariants(p)
+			}, nil)
+			So(err, ShouldBeNil)
+		})
+		Convey("Test invariants hold after any sequence of setters", func() {
+			err := quick.Check(func(records int32, sizes, pages []int16) bool {
+				p := common.NewPagination(1, common.DefaultPageSize, int(records))
+				p.SetTotalRecord(int(records))
+				for i := range sizes {
+					p.SetPageSize(int(sizes[i]))
+					if i < len(pages) {
+						p.SetPageNumber(int(pages[i]))
+					}
+					if !checkInvariants(p) {
+						return false
+					}
+				}
+				return checkInvariants(p)
+			}, nil)
+			So(err, ShouldBeNil)
+		})
+		Convey("Test page number is clamped or ignored", func() {
+			err := quick.Check(func(records uint16, pageNo int16) bool {
+				p := common.NewPagination(1, 10, 0)
+				p.SetTotalRecord(int(records))
+				before := p.PageNo
+				p.SetPageNumber(int(pageNo))
+				switch {
+				case pageNo < 1:
+					return p.PageNo == before
+				case int(pageNo) > p.TotalPages:
+					return p.PageNo == p.TotalPages
+				}
+				return p.PageNo == int(pageNo)
+			}, nil)
+			So(err, ShouldBeNil)
+		})
+		Convey("Test strict constructor rejects exactly the invalid inputs", func() {
+			err := quick.Check(func(pageNo, pageSize int16, records int32) bool {
+				p, err := common.NewPaginationStrict(int(pageNo), int(pageSize), int(records))
+				valid := pageNo >= 1 && pageSize >= 1 && int(pageSize) <= common.MaxPageSize && records >= 0
+				if !valid {
+					return p == nil && errors.Is(err, common.NewError(common.ErrCodeBadRequest))
+				}
+				return err == nil && p.PageSize == int(pageSize) && p.TotalRecords == int(records)
+			}, nil)
+			So(err, ShouldBeNil)
+		})
+	})
+	Convey("Test Pagination Fixes", t, func() {
+		Convey("Test page set to zero can be fixed", func() {
+			p := &common.Pagination{PageSize: 10}
+			p.SetPageNumber(3)
+			So(p.PageNumber(), ShouldEqual, 3)
+		})
+		Convey("Test zero page size does not divide by zero", func() {
+			p := &common.Pagination{}
+			So(func() { p.SetTotalRecord(15) }, ShouldNotPanic)
+			So(p.TotalPages, ShouldEqual, 1)
+		})
+		Convey("Test third argument is total records", func() {
+			p := common.NewPagination(5, 10, 25)
+			So(p.TotalRecords, ShouldEqual, 25)
+			So(p.TotalPages, ShouldEqual, 3)
+			So(p.PageNo, ShouldEqual, 3)
+		})
+		Convey("Test unknown totals keep requested page", func() {
+			p := common.NewPagination(5, 10, 0)
+			_, offset := p.Limit()
+			So(offset, ShouldEqual, 40)
+		})
+		Convey("Test current page is serialized", func() {
+			data, err := json.Marshal(common.NewPagination(2, 10, 30))
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `{"page":2,"pageSize":10,"totalPages":3,"totalRecords":30}`)
+		})
+	})
+}
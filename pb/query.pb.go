@@ -30,6 +30,7 @@ type Query struct {
 	Page     int32  `protobuf:"varint,2,opt,name=page,proto3" json:"page" validate:"required"`
 	Sorted   []byte `protobuf:"bytes,3,opt,name=sorted,proto3" json:"sorted"`
 	Filtered []byte `protobuf:"bytes,4,opt,name=filtered,proto3" json:"filtered"`
+	Fields   string `protobuf:"bytes,5,opt,name=fields,proto3" json:"fields,omitempty"`
 }
 
 func (m *Query) Reset()         { *m = Query{} }
@@ -74,24 +75,26 @@ func init() { proto.RegisterFile("pb/query.proto", fileDescriptor_5958159b9fcae2
 func init() { golang_proto.RegisterFile("pb/query.proto", fileDescriptor_5958159b9fcae252) }
 
 var fileDescriptor_5958159b9fcae252 = []byte{
-	// 270 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2b, 0x48, 0xd2, 0x2f,
-	0x2c, 0x4d, 0x2d, 0xaa, 0xd4, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0x62, 0x2a, 0x48, 0x92, 0xd2,
-	0x4d, 0xcf, 0x2c, 0xc9, 0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0x4f, 0xcf, 0x4f, 0xcf, 0xd7,
-	0x07, 0x4b, 0x25, 0x95, 0xa6, 0x81, 0x79, 0x60, 0x0e, 0x98, 0x05, 0xd1, 0xa2, 0x74, 0x9a, 0x91,
-	0x8b, 0x35, 0x10, 0x64, 0x84, 0x90, 0x3d, 0x17, 0x47, 0x41, 0x62, 0x7a, 0x6a, 0x70, 0x66, 0x55,
-	0xaa, 0x04, 0xa3, 0x02, 0xa3, 0x06, 0xab, 0x93, 0xf2, 0xab, 0x7b, 0xf2, 0x70, 0xb1, 0x4f, 0xf7,
-	0xe4, 0x85, 0xcb, 0x12, 0x73, 0x32, 0x53, 0x12, 0x4b, 0x52, 0xad, 0x94, 0x8a, 0x52, 0x0b, 0x4b,
-	0x33, 0x8b, 0x52, 0x53, 0x94, 0x82, 0xe0, 0x0a, 0x84, 0x8c, 0xb9, 0x58, 0x40, 0x6c, 0x09, 0x26,
-	0xb0, 0x66, 0xf9, 0x57, 0xf7, 0xe4, 0xc1, 0x7c, 0x5c, 0x1a, 0xc1, 0x92, 0x42, 0x4a, 0x5c, 0x6c,
-	0xc5, 0xf9, 0x45, 0x25, 0xa9, 0x29, 0x12, 0xcc, 0x0a, 0x8c, 0x1a, 0x3c, 0x4e, 0x5c, 0xaf, 0xee,
-	0xc9, 0x43, 0x45, 0x82, 0xa0, 0xb4, 0x90, 0x06, 0x17, 0x47, 0x5a, 0x66, 0x4e, 0x49, 0x6a, 0x51,
-	0x6a, 0x8a, 0x04, 0x0b, 0x58, 0x15, 0x0f, 0xc8, 0x65, 0x30, 0xb1, 0x20, 0x38, 0xcb, 0xc9, 0xe9,
-	0xc4, 0x43, 0x39, 0x86, 0x0b, 0x0f, 0xe5, 0x18, 0x4e, 0x3c, 0x92, 0x63, 0xbc, 0xf0, 0x48, 0x8e,
-	0xf1, 0xc1, 0x23, 0x39, 0xc6, 0x09, 0x8f, 0xe5, 0x18, 0x0e, 0x3c, 0x96, 0x63, 0xbc, 0xf0, 0x58,
-	0x8e, 0xe1, 0xc6, 0x63, 0x39, 0x86, 0x28, 0x19, 0xa4, 0x20, 0x4a, 0xcc, 0x29, 0xcd, 0x4e, 0xd4,
-	0x35, 0xd7, 0x4f, 0xce, 0xcf, 0xcd, 0xcd, 0xcf, 0xd3, 0x2f, 0x48, 0x4a, 0x62, 0x03, 0x07, 0x8c,
-	0x31, 0x20, 0x00, 0x00, 0xff, 0xff, 0xc6, 0x48, 0x29, 0x4f, 0x5d, 0x01, 0x00, 0x00,
+	// 301 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x90, 0xb1, 0x4e, 0xc3, 0x30,
+	0x18, 0x84, 0xe3, 0xd2, 0x56, 0xc5, 0xaa, 0x10, 0x32, 0x0c, 0x11, 0x42, 0x76, 0x15, 0x96, 0x0c,
+	0x6d, 0x33, 0x74, 0x40, 0x62, 0x41, 0xca, 0x1b, 0x10, 0x36, 0xb6, 0xb8, 0x71, 0x83, 0x45, 0x82,
+	0x5d, 0xd7, 0x41, 0x2a, 0x4f, 0xc1, 0x23, 0x31, 0x76, 0xcc, 0xc8, 0x14, 0xd1, 0x64, 0xcb, 0xc8,
+	0xc6, 0x86, 0xe2, 0x86, 0x88, 0x85, 0xc9, 0x77, 0xdf, 0x7f, 0x67, 0xfd, 0xfa, 0xe1, 0x89, 0xa4,
+	0xde, 0x3a, 0x63, 0x6a, 0x3b, 0x97, 0x4a, 0x68, 0x81, 0x7a, 0x92, 0x5e, 0xcc, 0x62, 0xae, 0x1f,
+	0x33, 0x3a, 0x5f, 0x8a, 0xd4, 0x8b, 0x45, 0x2c, 0x3c, 0x33, 0xa2, 0xd9, 0xca, 0x38, 0x63, 0x8c,
+	0x3a, 0x54, 0x9c, 0x6f, 0x00, 0x07, 0x77, 0xcd, 0x17, 0xe8, 0x16, 0x8e, 0x64, 0x18, 0xb3, 0x7b,
+	0xfe, 0xca, 0x6c, 0x30, 0x01, 0xee, 0xc0, 0xbf, 0xaa, 0x0b, 0xd2, 0xb1, 0xaf, 0x82, 0x9c, 0xbd,
+	0x84, 0x09, 0x8f, 0x42, 0xcd, 0x6e, 0x1c, 0xc5, 0xd6, 0x19, 0x57, 0x2c, 0x72, 0x82, 0x2e, 0x80,
+	0x16, 0xb0, 0xdf, 0x68, 0xbb, 0x67, 0xca, 0xa4, 0x2e, 0x88, 0xf1, 0xff, 0x15, 0xcd, 0x10, 0x39,
+	0x70, 0xb8, 0x11, 0x4a, 0xb3, 0xc8, 0x3e, 0x9a, 0x00, 0x77, 0xec, 0xc3, 0xba, 0x20, 0x2d, 0x09,
+	0xda, 0x17, 0xb9, 0x70, 0xb4, 0xe2, 0x89, 0x66, 0x8a, 0x45, 0x76, 0xdf, 0xa4, 0xc6, 0xcd, 0x66,
+	0xbf, 0x2c, 0xe8, 0x14, 0x9a, 0xc2, 0xe1, 0x8a, 0xb3, 0x24, 0xda, 0xd8, 0x83, 0x09, 0x70, 0x8f,
+	0xfd, 0xf3, 0xba, 0x20, 0xa7, 0x07, 0x32, 0x15, 0x29, 0xd7, 0x2c, 0x95, 0x7a, 0x1b, 0xb4, 0x19,
+	0xdf, 0xdf, 0xed, 0xb1, 0x95, 0xef, 0xb1, 0xb5, 0x2b, 0x31, 0xc8, 0x4b, 0x0c, 0x3e, 0x4b, 0x0c,
+	0xde, 0x2a, 0x6c, 0xbd, 0x57, 0x18, 0xe4, 0x15, 0xb6, 0x3e, 0x2a, 0x6c, 0x3d, 0x5c, 0xfe, 0x39,
+	0x68, 0x98, 0x64, 0x4f, 0xe1, 0xec, 0xda, 0x5b, 0x8a, 0x34, 0x15, 0xcf, 0x9e, 0xa4, 0x74, 0x68,
+	0xce, 0xb8, 0xf8, 0x19, 0x00, 0x67, 0xcb, 0xdb, 0x6c, 0x8b, 0x01, 0x00, 0x00,
 }
 
 func (m *Query) Marshal() (dAtA []byte, err error) {
@@ -114,6 +117,13 @@ func (m *Query) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Fields) > 0 {
+		i -= len(m.Fields)
+		copy(dAtA[i:], m.Fields)
+		i = encodeVarintQuery(dAtA, i, uint64(len(m.Fields)))
+		i--
+		dAtA[i] = 0x2a
+	}
 	if len(m.Filtered) > 0 {
 		i -= len(m.Filtered)
 		copy(dAtA[i:], m.Filtered)
@@ -172,6 +182,10 @@ func (m *Query) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovQuery(uint64(l))
 	}
+	l = len(m.Fields)
+	if l > 0 {
+		n += 1 + l + sovQuery(uint64(l))
+	}
 	return n
 }
 
@@ -316,6 +330,38 @@ func (m *Query) Unmarshal(dAtA []byte) error {
 				m.Filtered = []byte{}
 			}
 			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Fields", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowQuery
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthQuery
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthQuery
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Fields = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipQuery(dAtA[iNdEx:])
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// MIMEProblem RFC 9457 Problem Details的媒体类型
+const MIMEProblem = "application/problem+json"
+
+// ProblemTypeBase 问题类型URI的前缀，不为空时type为前缀加错误码(如https://api.example.com/problems/10001)，
+// title为错误码的默认消息；为空时type为about:blank，title为HTTP状态码的描述。
+var ProblemTypeBase = ""
+
+// Problem RFC 9457 Problem Details，code、opt和data为扩展成员，分别对应Result的错误码、操作编号和DtoResult的数据
+type Problem struct {
+	Type     string      `json:"type"`               // 问题类型URI
+	Title    string      `json:"title"`              // 问题类型的简短描述，同一类型的title保持不变
+	Status   int         `json:"status"`             // HTTP状态码
+	Detail   string      `json:"detail,omitempty"`   // 本次问题的具体描述，即Result.Message
+	Instance string      `json:"instance,omitempty"` // 发生问题的请求路径
+	Code     int         `json:"code"`               // 错误码，同Result.Code
+	Opt      string      `json:"opt,omitempty"`      // 操作编号，同Result.Opt
+	Data     interface{} `json:"data,omitempty"`     // 失败时附带的数据，如参数校验的错误列表
+}
+
+// NewProblem 根据失败的结果生成Problem Details，语言偏好取自上下文，用于生成title
+// @param           ctx         请求上下文
+// @param           r           失败的结果
+// @param           status      HTTP状态码
+// @param           instance    发生问题的请求路径
+func NewProblem(ctx context.Context, r Result, status int, instance string) Problem {
+	p := Problem{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: r.Message, Instance: instance, Code: r.Code, Opt: r.Opt}
+	if ProblemTypeBase != "" {
+		p.Type = ProblemTypeBase + strconv.Itoa(r.Code)
+		if c, ok := LookupCode(r.Code); ok {
+			p.Title = c.Message(LanguagesFromContext(ctx))
+		}
+	}
+	return p
+}
+
+// ProblemFromError 将错误转换为Problem Details，转换规则同ResultFromError
+func ProblemFromError(ctx context.Context, err error, instance string) Problem {
+	e := AsError(err)
+	r := e.Result()
+	if r.Opt == "" {
+		r.Opt = OptFromContext(ctx)
+	}
+	return NewProblem(ctx, r, e.Status().HTTP, instance)
+}
+
+// WriteProblem 不论请求的Accept头，总是将错误以Problem Details写入，用于只提供标准错误格式的接口
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) error {
+	p := ProblemFromError(r.Context(), err, r.URL.Path)
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, p, false); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", MIMEProblem)
+	w.WriteHeader(p.Status)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// Result 转换为Result
+func (p Problem) Result() Result {
+	return Result{Code: p.Code, Message: p.Detail, Opt: p.Opt}
+}
+
+// asProblem 将失败的返回结果转换为Problem Details，成功的结果或不支持的类型返回false
+func asProblem(r *http.Request, status int, v interface{}) (Problem, bool) {
+	var (
+		result Result
+		data   interface{}
+	)
+	switch t := v.(type) {
+	case Problem:
+		return t, true
+	case Result:
+		result = t
+	case DtoResult:
+		result, data = t.Result, t.Data
+	case PageResult:
+		result = t.Result
+	default:
+		return Problem{}, false
+	}
+	if result.Code == CodeOK.code {
+		return Problem{}, false
+	}
+	p := NewProblem(r.Context(), result, status, r.URL.Path)
+	p.Data = data
+	return p, true
+}
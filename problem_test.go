@@ -0,0 +1,75 @@
+package common_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProblem(t *testing.T) {
+	Convey("Test Problem Details", t, func() {
+		newRequest := func(accept string) *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/users/7?x=1", nil)
+			r.Header.Set("Accept", accept)
+			return r.WithContext(common.WithOpt(r.Context(), "opt-7"))
+		}
+
+		Convey("Test negotiated failure", func() {
+			rec := httptest.NewRecorder()
+			So(common.WriteFail(rec, newRequest("application/problem+json, application/json;q=0.5"), common.NewError(errCodeUserNotFound, 7)), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+			So(rec.Header().Get("Content-Type"), ShouldEqual, common.MIMEProblem)
+			var p common.Problem
+			So(json.Unmarshal(rec.Body.Bytes(), &p), ShouldBeNil)
+			So(p, ShouldResemble, common.Problem{
+				Type:     "about:blank",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   common.NewError(errCodeUserNotFound, 7).Message,
+				Instance: "/users/7",
+				Code:     errCodeUserNotFound.Code(),
+				Opt:      "opt-7",
+			})
+			So(p.Result().Code, ShouldEqual, errCodeUserNotFound.Code())
+		})
+		Convey("Test legacy envelope by default", func() {
+			for _, accept := range []string{"", "*/*", "application/json"} {
+				rec := httptest.NewRecorder()
+				So(common.WriteFail(rec, newRequest(accept), errors.New("boom")), ShouldBeNil)
+				So(rec.Code, ShouldEqual, http.StatusInternalServerError)
+				So(rec.Header().Get("Content-Type"), ShouldStartWith, common.MIMEJSON)
+				So(rec.Body.String(), ShouldContainSubstring, `"code":500`)
+			}
+		})
+		Convey("Test success ignores problem preference", func() {
+			rec := httptest.NewRecorder()
+			So(common.WriteOK(rec, newRequest(common.MIMEProblem), testUser{Id: 1}), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Header().Get("Content-Type"), ShouldStartWith, common.MIMEJSON)
+		})
+		Convey("Test failing dto keeps data and typed title", func() {
+			common.ProblemTypeBase = "https://api.example.com/problems/"
+			defer func() { common.ProblemTypeBase = "" }()
+			rec := httptest.NewRecorder()
+			dto := common.DtoResult{Result: common.Fail(common.ErrCodeBadRequest), Data: []string{"name"}}
+			So(common.WriteDto(rec, newRequest(common.MIMEProblem), dto), ShouldBeNil)
+			var p common.Problem
+			So(json.Unmarshal(rec.Body.Bytes(), &p), ShouldBeNil)
+			So(p.Type, ShouldEqual, "https://api.example.com/problems/400")
+			So(p.Title, ShouldEqual, "请求参数错误")
+			So(p.Data, ShouldResemble, []interface{}{"name"})
+		})
+		Convey("Test write problem unconditionally", func() {
+			rec := httptest.NewRecorder()
+			So(common.WriteProblem(rec, newRequest(""), common.NewError(common.ErrCodeNotFound)), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusNotFound)
+			So(rec.Header().Get("Content-Type"), ShouldEqual, common.MIMEProblem)
+			So(rec.Body.String(), ShouldContainSubstring, `"opt":"opt-7"`)
+		})
+	})
+}
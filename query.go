@@ -2,10 +2,12 @@ package common
 
 import (
 	"encoding/json"
+	"strings"
+
 	"github.com/aluka-7/common/pb"
 )
 
-//Query 查询参数结构体 {"pageSize":10,"page":0,"sorted":[{"id":"firstName","desc":false}],"filtered":[{"id":"firstName","value":"3"}]}
+//Query 查询参数结构体 {"pageSize":10,"page":0,"sorted":[{"id":"firstName","desc":false}],"filtered":[{"id":"firstName","value":"3"}],"fields":"id,firstName"}
 type Query struct {
 	PageSize int32 `json:"pageSize"`
 	Page     int32 `json:"page"`
@@ -17,6 +19,7 @@ type Query struct {
 		Id    string      `json:"id"`
 		Value interface{} `json:"value"`
 	} `json:"filtered"`
+	Fields string `json:"fields,omitempty"` // 返回字段投影，格式同ParseFields
 }
 
 func (sp *Query) SetPageSize(pageSize int32) {
@@ -37,6 +40,14 @@ func (sp *Query) SetFiltered(id string, value interface{}) {
 		Value interface{} `json:"value"`
 	}{Id: id, Value: value})
 }
+func (sp *Query) SetFields(paths ...string) {
+	sp.Fields = strings.Join(paths, ",")
+}
+
+// Projection 解析返回字段投影，可以通过WithFields保存到上下文中供WriteDto、WritePage使用
+func (sp Query) Projection() (Fields, error) {
+	return ParseFields(sp.Fields)
+}
 func (sp Query) MarkPage() *Pagination {
 	if sp.PageSize <= 0 {
 		sp.PageSize = 20
@@ -47,7 +58,7 @@ func (sp Query) MarkPage() *Pagination {
 	return NewPagination(int(sp.Page), int(sp.PageSize), 0)
 }
 func (sp *Query) ToPb() (pq *pb.Query) {
-	pq = &pb.Query{PageSize: sp.PageSize, Page: sp.Page}
+	pq = &pb.Query{PageSize: sp.PageSize, Page: sp.Page, Fields: sp.Fields}
 	if len(sp.Sorted) > 0 {
 		pq.Sorted, _ = json.Marshal(sp.Sorted)
 	}
@@ -59,6 +70,7 @@ func (sp *Query) ToPb() (pq *pb.Query) {
 func (sp *Query) ForPb(pq *pb.Query) *Query {
 	sp.PageSize = pq.PageSize
 	sp.Page = pq.Page
+	sp.Fields = pq.Fields
 	var sort []struct {
 		Id   string `json:"id"`
 		Desc bool   `json:"desc"`
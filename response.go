@@ -0,0 +1,230 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	MIMEJSON     = "application/json"
+	MIMEProtobuf = "application/x-protobuf"
+)
+
+type optKey struct{}
+
+// WithOpt 将操作编号保存到上下文中，写入返回结果时会自动填充到Opt
+func WithOpt(ctx context.Context, opt string) context.Context {
+	return context.WithValue(ctx, optKey{}, opt)
+}
+
+// OptFromContext 获取上下文中的操作编号
+func OptFromContext(ctx context.Context) string {
+	opt, _ := ctx.Value(optKey{}).(string)
+	return opt
+}
+
+// WriteOK 写入带有数据的成功结果
+func WriteOK(w http.ResponseWriter, r *http.Request, data interface{}) error {
+	return WriteDto(w, r, DtoResult{Result: OK(), Data: data})
+}
+
+// WriteFail 将错误转换为失败结果写入，HTTP状态码由错误码决定，规则同ResultFromError，
+// 参数校验失败(ValidationErrors)时写入Data为字段列表的DtoResult。
+func WriteFail(w http.ResponseWriter, r *http.Request, err error) error {
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		return write(w, r, http.StatusBadRequest, ve.DtoResult(r.Context()))
+	}
+	e := AsError(err)
+	if e.Code == ErrCodeInternal.code {
+		log.Err(err).Ctx(r.Context()).Msgf("处理请求[%s %s]发生错误", r.Method, r.URL.Path)
+	}
+	return write(w, r, e.Status().HTTP, e.Result())
+}
+
+// WriteResult 写入纯状态结果，HTTP状态码由错误码决定
+func WriteResult(w http.ResponseWriter, r *http.Request, result Result) error {
+	status, _ := statusOf(result.Code)
+	return write(w, r, status, result)
+}
+
+// WriteDto 写入带有数据的结果，HTTP状态码由错误码决定，请求指定了返回字段时对Data进行投影
+func WriteDto(w http.ResponseWriter, r *http.Request, dto DtoResult) error {
+	f, err := FieldsFromRequest(r)
+	if err == nil {
+		dto, err = dto.project(f, !IsTrusted(r.Context()))
+	}
+	if err != nil {
+		return WriteFail(w, r, err)
+	}
+	status, _ := statusOf(dto.Code)
+	return write(w, r, status, dto)
+}
+
+// WritePage 写入带有分页信息的成功结果，请求指定了返回字段时对List进行投影
+func WritePage(w http.ResponseWriter, r *http.Request, list interface{}, p *Pagination) error {
+	f, err := FieldsFromRequest(r)
+	if err == nil {
+		list, err = project(list, f, !IsTrusted(r.Context()))
+	}
+	if err != nil {
+		return WriteFail(w, r, err)
+	}
+	return write(w, r, http.StatusOK, NewPageResult[any](p, list))
+}
+
+// write 填充操作编号并脱敏，按请求的Accept头选择JSON、protobuf或Problem Details编码后写入，
+// 结果不支持protobuf编码时使用JSON，成功的结果不使用Problem Details。通过WithTrusted标记为可信的请求不进行脱敏。
+func write(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	if !IsTrusted(r.Context()) {
+		v = Mask(v)
+	}
+	v = fillOpt(v, OptFromContext(r.Context()))
+	mime := Negotiate(r.Header.Get("Accept"), MIMEJSON, MIMEProtobuf, MIMEProblem)
+	if mime == MIMEProblem {
+		if p, ok := asProblem(r, status, v); ok {
+			v = p
+		} else {
+			mime = MIMEJSON
+		}
+	}
+	buf := getBuffer()
+	defer putBuffer(buf)
+	contentType, err := encode(buf, mime, v)
+	if err != nil {
+		log.Err(err).Ctx(r.Context()).Msg("序列化返回结果发生错误")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return err
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func fillOpt(v interface{}, opt string) interface{} {
+	if opt == "" {
+		return v
+	}
+	switch t := v.(type) {
+	case Result:
+		if t.Opt == "" {
+			t.Opt = opt
+		}
+		return t
+	case DtoResult:
+		if t.Opt == "" {
+			t.Opt = opt
+		}
+		return t
+	case PageResult:
+		if t.Opt == "" {
+			t.Opt = opt
+		}
+		return t
+	}
+	return v
+}
+
+func encode(buf *bytes.Buffer, mime string, v interface{}) (contentType string, err error) {
+	if mime == MIMEProtobuf {
+		m, ok, err := toProto(v)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			body, err := proto.Marshal(m)
+			buf.Write(body)
+			return MIMEProtobuf, err
+		}
+	}
+	if mime == MIMEProblem {
+		return MIMEProblem, encodeJSON(buf, v, false)
+	}
+	return MIMEJSON + "; charset=utf-8", encodeJSON(buf, v, false)
+}
+
+// toProto 将返回结果转换为pb包中对应的protobuf消息，不支持的结果返回false
+func toProto(v interface{}) (proto.Message, bool, error) {
+	switch t := v.(type) {
+	case Result:
+		return t.ToPb(), true, nil
+	case DtoResult:
+		m, err := t.ToPb()
+		return m, err == nil, err
+	case PageResult:
+		m, err := t.ToPb()
+		return m, err == nil, err
+	case Callback:
+		return t.ToPb(), true, nil
+	case proto.Message:
+		return t, true, nil
+	}
+	return nil, false, nil
+}
+
+// Negotiate 根据Accept头从offers中选择权重最高的媒体类型，Accept为空或没有匹配时返回offers的第一个。
+// 每个offer使用匹配它的最具体的范围(type/subtype优先于type/*，再优先于*/*)的权重，
+// 如"application/json;q=0, */*"会排除application/json，权重相同时选择匹配范围在Accept中靠前的。
+func Negotiate(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	type mediaRange struct {
+		mime string
+		q    float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mime := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mime == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		ranges = append(ranges, mediaRange{mime, q})
+	}
+	best, bestQ, bestPos := offers[0], 0.0, len(ranges)
+	for _, offer := range offers {
+		pos, specificity := -1, 0
+		for i, mr := range ranges {
+			if s := mediaSpecificity(mr.mime, offer); s > specificity {
+				pos, specificity = i, s
+			}
+		}
+		if pos < 0 || ranges[pos].q <= 0 {
+			continue
+		}
+		if q := ranges[pos].q; q > bestQ || (q == bestQ && pos < bestPos) {
+			best, bestQ, bestPos = offer, q, pos
+		}
+	}
+	return best
+}
+
+// mediaSpecificity 返回媒体范围匹配offer的具体程度，不匹配为0，*/*为1，type/*为2，完全相同为3
+func mediaSpecificity(mime, offer string) int {
+	switch {
+	case mime == offer:
+		return 3
+	case strings.HasSuffix(mime, "/*") && strings.HasPrefix(offer, mime[:len(mime)-1]):
+		return 2
+	case mime == "*/*":
+		return 1
+	}
+	return 0
+}
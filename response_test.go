@@ -0,0 +1,87 @@
+package common_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aluka-7/common"
+	"github.com/aluka-7/common/pb"
+	"github.com/gogo/protobuf/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestResponse(t *testing.T) {
+	Convey("Test Response Writer", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req = req.WithContext(common.WithOpt(req.Context(), "op-1"))
+		rec := httptest.NewRecorder()
+
+		Convey("Test write ok", func() {
+			So(common.WriteOK(rec, req, testUser{1, "Aluka-7"}), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Header().Get("Content-Type"), ShouldEqual, "application/json; charset=utf-8")
+			var dto common.DtoResultOf[testUser]
+			So(json.Unmarshal(rec.Body.Bytes(), &dto), ShouldBeNil)
+			So(dto.Opt, ShouldEqual, "op-1")
+			So(dto.Data.Name, ShouldEqual, "Aluka-7")
+		})
+		Convey("Test write fail", func() {
+			So(common.WriteFail(rec, req, common.NewError(common.ErrCodeNotFound)), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusNotFound)
+			var r common.Result
+			So(json.Unmarshal(rec.Body.Bytes(), &r), ShouldBeNil)
+			So(r.Code, ShouldEqual, 404)
+			So(r.Opt, ShouldEqual, "op-1")
+
+			rec = httptest.NewRecorder()
+			So(common.WriteFail(rec, req, errors.New("boom")), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusInternalServerError)
+		})
+		Convey("Test write page", func() {
+			p := common.DefaultPagination()
+			p.SetTotalRecord(2)
+			So(common.WritePage(rec, req, []testUser{{1, "Aluka-7"}, {2, "Brandon"}}, p), ShouldBeNil)
+			page, err := common.DecodePage[[]testUser](rec.Body)
+			So(err, ShouldBeNil)
+			So(page.Opt, ShouldEqual, "op-1")
+			So(page.Pagination.TotalRecords, ShouldEqual, 2)
+			So(len(page.List), ShouldEqual, 2)
+		})
+		Convey("Test protobuf negotiation", func() {
+			req.Header.Set("Accept", "application/x-protobuf, application/json;q=0.5")
+			So(common.WriteResult(rec, req, common.Fail(common.ErrCodeForbidden)), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusForbidden)
+			So(rec.Header().Get("Content-Type"), ShouldEqual, common.MIMEProtobuf)
+			var pr pb.Result
+			So(proto.Unmarshal(rec.Body.Bytes(), &pr), ShouldBeNil)
+			So(pr.Code, ShouldEqual, 403)
+			So(pr.Opt, ShouldEqual, "op-1")
+		})
+		Convey("Test protobuf dto", func() {
+			req.Header.Set("Accept", common.MIMEProtobuf)
+			So(common.WriteOK(rec, req, testUser{1, "Aluka-7"}), ShouldBeNil)
+			var pd pb.DtoResult
+			So(proto.Unmarshal(rec.Body.Bytes(), &pd), ShouldBeNil)
+			var dto common.DtoResultOf[testUser]
+			So(dto.ForPb(&pd), ShouldBeNil)
+			So(dto.Data, ShouldResemble, testUser{1, "Aluka-7"})
+			So(dto.Opt, ShouldEqual, "op-1")
+		})
+		Convey("Test negotiate", func() {
+			So(common.Negotiate("", common.MIMEJSON, common.MIMEProtobuf), ShouldEqual, common.MIMEJSON)
+			So(common.Negotiate("application/*;q=0.8, text/html", common.MIMEProtobuf), ShouldEqual, common.MIMEProtobuf)
+			So(common.Negotiate("application/x-protobuf;q=0", common.MIMEJSON, common.MIMEProtobuf), ShouldEqual, common.MIMEJSON)
+			So(common.Negotiate("application/json;q=0, */*", common.MIMEJSON, common.MIMEProtobuf), ShouldEqual, common.MIMEProtobuf)
+			So(common.Negotiate("*/*;q=0.9, application/*;q=0.5, application/json", common.MIMEProtobuf, common.MIMEJSON), ShouldEqual, common.MIMEJSON)
+			So(common.Negotiate("application/x-protobuf, application/json", common.MIMEJSON, common.MIMEProtobuf), ShouldEqual, common.MIMEProtobuf)
+		})
+		Convey("Test nil pagination falls back to default", func() {
+			rec := httptest.NewRecorder()
+			So(common.WritePage(rec, httptest.NewRequest(http.MethodGet, "/", nil), []int{1}, nil), ShouldBeNil)
+			So(rec.Body.String(), ShouldContainSubstring, `"pagination":{"page":1,"pageSize":20`)
+		})
+	})
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"context"
+	"sync"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+)
+
+// NewMemoryStore 创建基于内存的会话存储，适用于单实例部署和测试，多实例部署需要使用共享存储实现Store。
+func NewMemoryStore() Store {
+	return &memoryStore{sessions: make(map[string]Session), bySubject: make(map[string]map[string]struct{})}
+}
+
+type memoryStore struct {
+	mu        sync.RWMutex
+	sessions  map[string]Session             // jti -> 会话
+	bySubject map[string]map[string]struct{} // subject -> jti集合
+}
+
+// Save 保存会话，同时清理该用户已过期的会话，避免不再调用List的用户的会话一直占用内存
+func (ms *memoryStore) Save(ctx context.Context, s Session) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.save(s)
+	return nil
+}
+
+// Record 实现Recorder，在同一把锁内完成淘汰和保存
+func (ms *memoryStore) Record(ctx context.Context, s Session, limit int) ([]Session, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	evicted := Evictions(ms.list(s.Subject), s, limit)
+	for _, o := range evicted {
+		ms.delete(o.Jti)
+	}
+	ms.save(s)
+	return evicted, nil
+}
+
+func (ms *memoryStore) save(s Session) {
+	ms.purge(s.Subject)
+	ms.sessions[s.Jti] = s
+	jtis, ok := ms.bySubject[s.Subject]
+	if !ok {
+		jtis = make(map[string]struct{})
+		ms.bySubject[s.Subject] = jtis
+	}
+	jtis[s.Jti] = struct{}{}
+}
+
+func (ms *memoryStore) Get(ctx context.Context, jti string) (Session, bool, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	s, ok := ms.sessions[jti]
+	if !ok || s.Expired(jwtgo.TimeFunc()) {
+		return Session{}, false, nil
+	}
+	return s, true, nil
+}
+
+func (ms *memoryStore) List(ctx context.Context, subject string) ([]Session, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.list(subject), nil
+}
+
+func (ms *memoryStore) list(subject string) []Session {
+	ms.purge(subject)
+	var sessions []Session
+	for jti := range ms.bySubject[subject] {
+		sessions = append(sessions, ms.sessions[jti])
+	}
+	return sessions
+}
+
+// purge 删除用户已过期的会话
+func (ms *memoryStore) purge(subject string) {
+	now := jwtgo.TimeFunc()
+	for jti := range ms.bySubject[subject] {
+		if ms.sessions[jti].Expired(now) {
+			ms.delete(jti)
+		}
+	}
+}
+
+func (ms *memoryStore) Delete(ctx context.Context, jti string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.delete(jti)
+	return nil
+}
+
+func (ms *memoryStore) delete(jti string) {
+	s, ok := ms.sessions[jti]
+	if !ok {
+		return
+	}
+	delete(ms.sessions, jti)
+	if jtis := ms.bySubject[s.Subject]; jtis != nil {
+		delete(jtis, jti)
+		if len(jtis) == 0 {
+			delete(ms.bySubject, s.Subject)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package session 会话管理，记录每个签发token的jti与设备信息的对应关系，
+// 支持查询用户的活跃会话、注销单个会话以及按设备类别限制同时在线的会话数。
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aluka-7/common"
+	"github.com/aluka-7/common/jwt"
+	jwtgo "github.com/dgrijalva/jwt-go"
+)
+
+// ErrRevoked token对应的会话不存在、已被注销或已过期
+var ErrRevoked = errors.New("会话已失效")
+
+// Session 一次登录产生的会话信息
+type Session struct {
+	Jti       string    `json:"jti"`       // JWT token 的唯一标识符
+	Subject   string    `json:"sub"`       // JWT token 的主体，一般为用户标识
+	Device    string    `json:"device"`    // 客户端设备载体，见AppVersion.Device
+	Version   string    `json:"version"`   // 客户端版本信息
+	Network   string    `json:"network"`   // 登录时的网络情况
+	IP        string    `json:"ip"`        // 登录时的客户端IP
+	IssuedAt  time.Time `json:"issuedAt"`  // 签发时间
+	ExpiresAt time.Time `json:"expiresAt"` // 过期时间
+}
+
+// AppVersion 返回会话登录时的客户端版本信息
+func (s Session) AppVersion() common.AppVersion {
+	return common.NewAppVersion(s.Device, s.Version, s.Network)
+}
+
+// DeviceClass 返回会话的设备类别，Native或Web，用于限制同类设备的在线数
+func (s Session) DeviceClass() string {
+	return s.AppVersion().SimpleDevice()
+}
+
+// Expired 判断会话在给定时间是否已过期
+func (s Session) Expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// Store 会话存储，实现需要保证并发安全，过期的会话不应再由Get和List返回。
+type Store interface {
+	// Save 保存会话，jti相同时覆盖
+	Save(ctx context.Context, s Session) error
+	// Get 根据jti获取会话，不存在或已过期时ok为false
+	Get(ctx context.Context, jti string) (s Session, ok bool, err error)
+	// List 获取用户所有未过期的会话
+	List(ctx context.Context, subject string) ([]Session, error)
+	// Delete 删除会话，会话不存在时不返回错误
+	Delete(ctx context.Context, jti string) error
+}
+
+// Recorder 可选接口，Store实现后Manager通过它在一次原子操作中完成淘汰和保存。
+// 未实现时Manager只在当前进程内按用户加锁，多实例共享存储时需要实现该接口才能严格限制在线数。
+type Recorder interface {
+	// Record 保存会话，并注销同一用户同一设备类别下超出limit的最早的会话，返回被注销的会话
+	Record(ctx context.Context, s Session, limit int) (evicted []Session, err error)
+}
+
+// Manager 会话管理器
+type Manager struct {
+	store  Store
+	limits map[string]int // 每种设备类别允许同时在线的会话数
+
+	mu    sync.Mutex
+	locks map[string]*subjectLock // 用户 -> 记录会话时持有的锁
+}
+
+type subjectLock struct {
+	sync.Mutex
+	refs int
+}
+
+// NewManager 创建会话管理器，limits为每种设备类别(Native/Web)允许同时在线的会话数，
+// 如map[string]int{"Native": 1, "Web": 3}，未配置或小于等于0的类别不限制。
+func NewManager(store Store, limits map[string]int) *Manager {
+	return &Manager{store: store, limits: limits, locks: make(map[string]*subjectLock)}
+}
+
+// Issue 签发token并记录会话，超出设备类别的在线数限制时注销该类别下最早的会话。
+// @param           tp          已加载密钥的TokenProvider
+// @param           aud         JWT token 的收件人
+// @param           iss         JWT token 的发件人
+// @param           sub         JWT token 的主体
+// @param           claims      用户信息
+// @param           av          客户端版本信息
+// @param           ip          客户端IP
+// @return          signed      JWT token 签名
+// @return          evicted     因超出在线数限制被注销的会话
+func (m *Manager) Issue(ctx context.Context, tp jwt.TokenProvider, aud, iss, sub string, claims jwt.UserClaims, av common.AppVersion, ip string) (signed string, evicted []Session, err error) {
+	jti := newJti()
+	signed = tp.CreateToken(aud, iss, sub, jti, jwtgo.TimeFunc().Unix(), claims)
+	if signed == "" {
+		return "", nil, errors.New("签发token失败")
+	}
+	var sc jwtgo.StandardClaims
+	if _, _, err = new(jwtgo.Parser).ParseUnverified(signed, &sc); err != nil {
+		return "", nil, err
+	}
+	evicted, err = m.Record(ctx, Session{
+		Jti:       jti,
+		Subject:   sub,
+		Device:    av.Device(),
+		Version:   av.Version(),
+		Network:   av.Network(),
+		IP:        ip,
+		IssuedAt:  time.Unix(sc.IssuedAt, 0),
+		ExpiresAt: time.Unix(sc.ExpiresAt, 0),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, evicted, nil
+}
+
+// Record 记录一个已签发token的会话，超出设备类别的在线数限制时注销该类别下最早的会话。
+// Store实现了Recorder时由存储保证原子性，否则同一用户的记录在当前进程内串行执行。
+func (m *Manager) Record(ctx context.Context, s Session) (evicted []Session, err error) {
+	limit := m.limits[s.DeviceClass()]
+	if limit <= 0 {
+		return nil, m.store.Save(ctx, s)
+	}
+	if r, ok := m.store.(Recorder); ok {
+		return r.Record(ctx, s, limit)
+	}
+	unlock := m.lock(s.Subject)
+	defer unlock()
+	sessions, err := m.store.List(ctx, s.Subject)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range Evictions(sessions, s, limit) {
+		if err := m.store.Delete(ctx, o.Jti); err != nil {
+			return evicted, err
+		}
+		evicted = append(evicted, o)
+	}
+	return evicted, m.store.Save(ctx, s)
+}
+
+// Evictions 返回保存s前需要注销的会话：sessions中与s同一设备类别的会话按签发时间由旧到新排列，
+// 签发时间相同时按jti排列，保留最新的limit-1个，供Recorder的实现复用。
+func Evictions(sessions []Session, s Session, limit int) []Session {
+	var same []Session
+	for _, o := range sessions {
+		if o.Jti != s.Jti && o.DeviceClass() == s.DeviceClass() {
+			same = append(same, o)
+		}
+	}
+	if len(same) < limit {
+		return nil
+	}
+	sort.Slice(same, func(i, j int) bool {
+		if !same[i].IssuedAt.Equal(same[j].IssuedAt) {
+			return same[i].IssuedAt.Before(same[j].IssuedAt)
+		}
+		return same[i].Jti < same[j].Jti
+	})
+	return same[:len(same)-limit+1]
+}
+
+// lock 获取用户的记录锁，返回释放函数，没有等待者时回收锁
+func (m *Manager) lock(subject string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[subject]
+	if !ok {
+		l = &subjectLock{}
+		m.locks[subject] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		m.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(m.locks, subject)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// Verify 校验token并确认其会话仍然有效，会话被注销后即使token未过期也返回ErrRevoked。
+func (m *Manager) Verify(ctx context.Context, tp jwt.TokenProvider, tokenString, aud, iss string) (s Session, claims jwt.UserClaims, err error) {
+	jti, claims, err := tp.VerifyToken(tokenString, aud, iss)
+	if err != nil {
+		return s, claims, err
+	}
+	s, ok, err := m.store.Get(ctx, jti)
+	if err != nil {
+		return s, claims, err
+	}
+	if !ok {
+		return s, claims, ErrRevoked
+	}
+	return s, claims, nil
+}
+
+// List 获取用户所有活跃的会话，按签发时间由新到旧排列
+func (m *Manager) List(ctx context.Context, subject string) ([]Session, error) {
+	sessions, err := m.store.List(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].IssuedAt.Equal(sessions[j].IssuedAt) {
+			return sessions[i].IssuedAt.After(sessions[j].IssuedAt)
+		}
+		return sessions[i].Jti > sessions[j].Jti
+	})
+	return sessions, nil
+}
+
+// Revoke 注销单个会话
+func (m *Manager) Revoke(ctx context.Context, jti string) error {
+	return m.store.Delete(ctx, jti)
+}
+
+// RevokeOthers 注销用户除当前会话外的所有会话，即"退出其他设备"
+func (m *Manager) RevokeOthers(ctx context.Context, subject, currentJti string) error {
+	sessions, err := m.store.List(ctx, subject)
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if s.Jti == currentJti {
+			continue
+		}
+		if err := m.store.Delete(ctx, s.Jti); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newJti() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
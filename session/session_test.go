@@ -0,0 +1,86 @@
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aluka-7/common"
+	"github.com/aluka-7/common/jwt"
+	"github.com/aluka-7/common/session"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSession(t *testing.T) {
+	Convey("Test Session Manager", t, func() {
+		ctx := context.Background()
+		jwt.JwtTokenProvider.Load("buzkd&yshKl#Si", 3600)
+		tp := jwt.JwtTokenProvider
+		m := session.NewManager(session.NewMemoryStore(), map[string]int{"Native": 1, "Web": 3})
+		ios := common.NewAppVersion("iOS-Native", "2.3.1", "wifi")
+		web := common.NewAppVersion("Web", "2.3.1", "unknown")
+
+		Convey("Test native sessions are capped", func() {
+			first, _, err := m.Issue(ctx, tp, "gateway", "web", "u1", jwt.UserClaims{UId: 1}, ios, "10.0.0.1")
+			So(err, ShouldBeNil)
+			_, evicted, err := m.Issue(ctx, tp, "gateway", "web", "u1", jwt.UserClaims{UId: 1}, ios, "10.0.0.2")
+			So(err, ShouldBeNil)
+			So(len(evicted), ShouldEqual, 1)
+			So(evicted[0].IP, ShouldEqual, "10.0.0.1")
+
+			_, _, err = m.Verify(ctx, tp, first, "gateway", "web")
+			So(err, ShouldEqual, session.ErrRevoked)
+		})
+		Convey("Test list and revoke others", func() {
+			current, _, _ := m.Issue(ctx, tp, "gateway", "web", "u2", jwt.UserClaims{}, web, "10.0.0.1")
+			_, _, _ = m.Issue(ctx, tp, "gateway", "web", "u2", jwt.UserClaims{}, web, "10.0.0.2")
+			_, _, _ = m.Issue(ctx, tp, "gateway", "web", "u2", jwt.UserClaims{}, ios, "10.0.0.3")
+			sessions, err := m.List(ctx, "u2")
+			So(err, ShouldBeNil)
+			So(len(sessions), ShouldEqual, 3)
+
+			s, _, err := m.Verify(ctx, tp, current, "gateway", "web")
+			So(err, ShouldBeNil)
+			So(s.AppVersion().IsWeb(), ShouldBeTrue)
+			So(m.RevokeOthers(ctx, "u2", s.Jti), ShouldBeNil)
+			sessions, _ = m.List(ctx, "u2")
+			So(len(sessions), ShouldEqual, 1)
+			So(sessions[0].Jti, ShouldEqual, s.Jti)
+
+			So(m.Revoke(ctx, s.Jti), ShouldBeNil)
+			_, _, err = m.Verify(ctx, tp, current, "gateway", "web")
+			So(err, ShouldEqual, session.ErrRevoked)
+		})
+		Convey("Test concurrent logins respect the cap", func() {
+			// struct{ session.Store }隐藏了Recorder，走Manager按用户加锁的路径
+			for _, store := range []session.Store{session.NewMemoryStore(), struct{ session.Store }{session.NewMemoryStore()}} {
+				m := session.NewManager(store, map[string]int{"Native": 2})
+				done := make(chan error, 16)
+				for i := 0; i < cap(done); i++ {
+					go func() {
+						_, _, err := m.Issue(ctx, tp, "gateway", "web", "u3", jwt.UserClaims{}, ios, "10.0.0.1")
+						done <- err
+					}()
+				}
+				for i := 0; i < cap(done); i++ {
+					So(<-done, ShouldBeNil)
+				}
+				sessions, _ := m.List(ctx, "u3")
+				So(len(sessions), ShouldEqual, 2)
+			}
+		})
+		Convey("Test evictions break ties by jti", func() {
+			at := time.Unix(1700000000, 0)
+			sessions := []session.Session{
+				{Jti: "c", Device: "Android-Native", IssuedAt: at},
+				{Jti: "a", Device: "Android-Native", IssuedAt: at},
+				{Jti: "b", Device: "Android-Native", IssuedAt: at},
+				{Jti: "w", Device: "Web", IssuedAt: at.Add(-time.Hour)},
+			}
+			evicted := session.Evictions(sessions, session.Session{Jti: "d", Device: "iOS-Native", IssuedAt: at}, 2)
+			So(len(evicted), ShouldEqual, 2)
+			So(evicted[0].Jti, ShouldEqual, "a")
+			So(evicted[1].Jti, ShouldEqual, "b")
+		})
+	})
+}
@@ -0,0 +1,130 @@
+package common
+
+import (
+	"net/http"
+	"sort"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Status 错误码对应的传输层状态
+type Status struct {
+	HTTP      int        // HTTP状态码，为0时使用错误码本身，错误码不是合法的HTTP状态码时使用fallback的HTTP状态码
+	GRPC      codes.Code // gRPC状态码
+	Retryable bool       // 调用方是否可以重试
+}
+
+type statusRule struct {
+	min, max int
+	status   Status
+	seq      int // 添加顺序，范围相同时后添加的优先
+}
+
+// StatusPolicy 错误码到HTTP、gRPC状态码和可重试性的映射策略，按错误码范围配置，范围越小优先级越高。
+// 服务端写入结果和客户端解释结果都使用同一策略，保证双方对同一错误码的理解一致。
+type StatusPolicy struct {
+	rules    []statusRule
+	fallback Status
+}
+
+// NewStatusPolicy 创建映射策略，fallback为没有匹配任何范围时使用的状态
+func NewStatusPolicy(fallback Status) *StatusPolicy {
+	return &StatusPolicy{fallback: fallback}
+}
+
+// Map 将错误码范围[min, max]映射到给定的状态，返回策略本身以便链式调用
+func (p *StatusPolicy) Map(min, max int, s Status) *StatusPolicy {
+	p.rules = append(p.rules, statusRule{min: min, max: max, status: s, seq: len(p.rules)})
+	sort.SliceStable(p.rules, func(i, j int) bool {
+		wi, wj := p.rules[i].max-p.rules[i].min, p.rules[j].max-p.rules[j].min
+		if wi != wj {
+			return wi < wj
+		}
+		return p.rules[i].seq > p.rules[j].seq
+	})
+	return p
+}
+
+// MapCode 将单个错误码映射到给定的状态
+func (p *StatusPolicy) MapCode(c Code, s Status) *StatusPolicy {
+	return p.Map(c.code, c.code, s)
+}
+
+// Status 返回错误码对应的状态，HTTP为0的规则已替换为错误码本身，
+// 错误码不在100~599范围内时替换为fallback的HTTP状态码，fallback同样为0时使用500
+func (p *StatusPolicy) Status(code int) Status {
+	s := p.fallback
+	for _, r := range p.rules {
+		if code >= r.min && code <= r.max {
+			s = r.status
+			break
+		}
+	}
+	if s.HTTP == 0 {
+		switch {
+		case code >= 100 && code <= 599:
+			s.HTTP = code
+		case p.fallback.HTTP != 0:
+			s.HTTP = p.fallback.HTTP
+		default:
+			s.HTTP = http.StatusInternalServerError
+		}
+	}
+	return s
+}
+
+// Retryable 判断错误码对应的失败是否可以重试
+func (p *StatusPolicy) Retryable(code int) bool {
+	return p.Status(code).Retryable
+}
+
+// DefaultStatusPolicy 默认的映射策略：
+//   - 200成功；0为SDK错误，按内部错误处理。
+//   - 公共错误码映射到对应的HTTP和gRPC状态码，429、502、503、504可以重试。
+//   - 其他400~599范围内的错误码直接作为HTTP状态码。
+//   - 业务错误码按请求错误(400、FailedPrecondition)处理。
+func DefaultStatusPolicy() *StatusPolicy {
+	return NewStatusPolicy(Status{HTTP: http.StatusBadRequest, GRPC: codes.FailedPrecondition}).
+		Map(0, 0, Status{HTTP: http.StatusInternalServerError, GRPC: codes.Internal}).
+		Map(400, 499, Status{GRPC: codes.FailedPrecondition}).
+		Map(500, 599, Status{GRPC: codes.Internal}).
+		Map(http.StatusTooManyRequests, http.StatusTooManyRequests, Status{HTTP: http.StatusTooManyRequests, GRPC: codes.ResourceExhausted, Retryable: true}).
+		Map(http.StatusBadGateway, http.StatusBadGateway, Status{HTTP: http.StatusBadGateway, GRPC: codes.Unavailable, Retryable: true}).
+		MapCode(CodeOK, Status{HTTP: http.StatusOK, GRPC: codes.OK}).
+		MapCode(ErrCodeBadRequest, Status{HTTP: http.StatusBadRequest, GRPC: codes.InvalidArgument}).
+		MapCode(ErrCodeUnauthorized, Status{HTTP: http.StatusUnauthorized, GRPC: codes.Unauthenticated}).
+		MapCode(ErrCodeForbidden, Status{HTTP: http.StatusForbidden, GRPC: codes.PermissionDenied}).
+		MapCode(ErrCodeNotFound, Status{HTTP: http.StatusNotFound, GRPC: codes.NotFound}).
+		MapCode(ErrCodeCanceled, Status{HTTP: ErrCodeCanceled.code, GRPC: codes.Canceled}).
+		MapCode(ErrCodeInternal, Status{HTTP: http.StatusInternalServerError, GRPC: codes.Internal}).
+		MapCode(ErrCodeUnavailable, Status{HTTP: http.StatusServiceUnavailable, GRPC: codes.Unavailable, Retryable: true}).
+		MapCode(ErrCodeTimeout, Status{HTTP: http.StatusGatewayTimeout, GRPC: codes.DeadlineExceeded, Retryable: true})
+}
+
+var statusPolicy atomic.Value
+
+func init() {
+	SetStatusPolicy(DefaultStatusPolicy())
+}
+
+// SetStatusPolicy 替换全局的映射策略，应在程序启动时调用，策略设置后不应再修改
+func SetStatusPolicy(p *StatusPolicy) {
+	statusPolicy.Store(p)
+}
+
+// CurrentStatusPolicy 返回当前使用的映射策略
+func CurrentStatusPolicy() *StatusPolicy {
+	return statusPolicy.Load().(*StatusPolicy)
+}
+
+// statusOf 根据当前策略推导HTTP和gRPC状态码
+func statusOf(code int) (int, codes.Code) {
+	s := CurrentStatusPolicy().Status(code)
+	return s.HTTP, s.GRPC
+}
+
+// Retryable 按当前策略判断错误是否可以重试，错误的转换规则同AsError，nil返回false
+func Retryable(err error) bool {
+	return err != nil && CurrentStatusPolicy().Retryable(AsError(err).Code)
+}
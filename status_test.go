@@ -0,0 +1,96 @@
+package common_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusPolicy(t *testing.T) {
+	Convey("Test Status Policy", t, func() {
+		p := common.DefaultStatusPolicy()
+
+		Convey("Test default mapping", func() {
+			So(p.Status(200), ShouldResemble, common.Status{HTTP: 200, GRPC: codes.OK})
+			So(p.Status(0).HTTP, ShouldEqual, http.StatusInternalServerError)
+			So(p.Status(404), ShouldResemble, common.Status{HTTP: 404, GRPC: codes.NotFound})
+			So(p.Status(418), ShouldResemble, common.Status{HTTP: 418, GRPC: codes.FailedPrecondition})
+			So(p.Status(errCodeUserNotFound.Code()), ShouldResemble, common.Status{HTTP: 400, GRPC: codes.FailedPrecondition})
+			So(p.Retryable(503), ShouldBeTrue)
+			So(p.Retryable(429), ShouldBeTrue)
+			So(p.Retryable(500), ShouldBeFalse)
+			So(common.Retryable(context.DeadlineExceeded), ShouldBeTrue)
+			So(common.Retryable(common.NewError(errCodeUserNotFound)), ShouldBeFalse)
+			So(common.Retryable(nil), ShouldBeFalse)
+		})
+		Convey("Test narrower ranges win", func() {
+			p := common.NewStatusPolicy(common.Status{HTTP: 400}).
+				Map(20000, 29999, common.Status{HTTP: 503, GRPC: codes.Unavailable, Retryable: true}).
+				Map(20000, 20099, common.Status{HTTP: 409, GRPC: codes.Aborted})
+			So(p.Status(20050).HTTP, ShouldEqual, 409)
+			So(p.Status(20100).Retryable, ShouldBeTrue)
+			So(p.Status(1).HTTP, ShouldEqual, 400)
+		})
+		Convey("Test errors resolve status when written", func() {
+			err := common.NewError(errCodeUserNotFound, "tom")
+			common.SetStatusPolicy(common.DefaultStatusPolicy().
+				MapCode(errCodeUserNotFound, common.Status{HTTP: http.StatusNotFound, GRPC: codes.NotFound}))
+			defer common.SetStatusPolicy(common.DefaultStatusPolicy())
+
+			rec := httptest.NewRecorder()
+			So(common.WriteFail(rec, httptest.NewRequest(http.MethodGet, "/", nil), err), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusNotFound)
+			So(status.Code(err), ShouldEqual, codes.NotFound)
+			So(common.ProblemFromError(context.Background(), err, "").Status, ShouldEqual, http.StatusNotFound)
+
+			err.HTTPStatus = http.StatusConflict
+			So(err.Status().HTTP, ShouldEqual, http.StatusConflict)
+			So(err.Status().GRPC, ShouldEqual, codes.NotFound)
+		})
+		Convey("Test business codes without HTTP use the fallback", func() {
+			p := common.DefaultStatusPolicy().Map(10000, 19999, common.Status{GRPC: codes.Aborted})
+			So(p.Status(10001), ShouldResemble, common.Status{HTTP: http.StatusBadRequest, GRPC: codes.Aborted})
+			So(common.NewStatusPolicy(common.Status{}).Status(10001).HTTP, ShouldEqual, http.StatusInternalServerError)
+
+			common.SetStatusPolicy(p)
+			defer common.SetStatusPolicy(common.DefaultStatusPolicy())
+			rec := httptest.NewRecorder()
+			So(common.WriteResult(rec, httptest.NewRequest(http.MethodGet, "/", nil), common.Fail(errCodeUserNotFound, 1)), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("Test writers and clients use the global policy", func() {
+			common.SetStatusPolicy(common.DefaultStatusPolicy().
+				Map(10000, 19999, common.Status{HTTP: http.StatusConflict, GRPC: codes.Aborted, Retryable: true}))
+			defer common.SetStatusPolicy(common.DefaultStatusPolicy())
+
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				_ = common.WriteResult(w, r, common.Fail(errCodeUserNotFound, 1))
+			}))
+			defer srv.Close()
+			c := common.NewClient(srv.URL)
+			c.MaxRetries, c.Backoff = 2, time.Millisecond
+			err := c.Get(context.Background(), "/", nil)
+			var e *common.Error
+			So(errors.As(err, &e), ShouldBeTrue)
+			So(e.HTTPStatus, ShouldEqual, http.StatusConflict)
+			So(e.Status().GRPC, ShouldEqual, codes.Aborted)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+
+			c.Policy = common.DefaultStatusPolicy()
+			atomic.StoreInt32(&calls, 0)
+			_ = c.Get(context.Background(), "/", nil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,502 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	MIMENDJSON = "application/x-ndjson"
+	MIMECSV    = "text/csv"
+)
+
+const (
+	defaultFlushEvery    = 100
+	defaultFlushInterval = time.Second
+	streamBufferSize     = 32 << 10
+	resultCodeTrailer    = "X-Result-Code" // CSV格式在尾部字段中返回最终的错误码
+)
+
+// Iterator 逐条获取记录，没有更多记录时ok返回false
+type Iterator[T any] func(ctx context.Context) (item T, ok bool, err error)
+
+// SliceIterator 逐条返回切片中的记录
+func SliceIterator[T any](list []T) Iterator[T] {
+	i := 0
+	return func(ctx context.Context) (item T, ok bool, err error) {
+		if i >= len(list) {
+			return item, false, nil
+		}
+		i++
+		return list[i-1], true, nil
+	}
+}
+
+// ChanIterator 逐条返回通道中的记录，通道关闭后结束，上下文取消时返回上下文的错误
+func ChanIterator[T any](ch <-chan T) Iterator[T] {
+	return func(ctx context.Context) (item T, ok bool, err error) {
+		select {
+		case item, ok = <-ch:
+			return item, ok, nil
+		case <-ctx.Done():
+			return item, false, ctx.Err()
+		}
+	}
+}
+
+// CSVMarshaler 自定义记录的CSV表头和行数据，未实现时按结构体字段的JSON名称生成
+type CSVMarshaler interface {
+	CSVHeader() []string
+	CSVRecord() []string
+}
+
+// StreamConfig 流式写入的配置
+type StreamConfig struct {
+	Format        string        // MIMEJSON、MIMENDJSON或MIMECSV，为空时使用MIMEJSON
+	FlushEvery    int           // 每写入多少条记录刷新一次，默认100
+	FlushInterval time.Duration // 距离上次刷新超过该时间时刷新，默认1秒，获取记录阻塞时同样会按该间隔刷新已写入的数据
+	Trusted       bool          // 为true时不对记录进行脱敏
+}
+
+// streamHead 分页结果除记录集以外的部分，字段顺序与PageResult一致
+type streamHead struct {
+	Result
+	Pagination Page `json:"pagination"`
+}
+
+// streamError 记录写入过程中发生的错误，此时状态码已经写出，错误追加在结果末尾
+type streamError struct {
+	Error Result `json:"error"`
+}
+
+// EncodeStream 将分页结果逐条编码写入w，内存占用与记录总数无关，返回写入的记录数。
+//   - MIMEJSON：与PageResult相同的JSON，记录逐条写入data数组。
+//   - MIMENDJSON：首行为不含记录集的分页结果，之后每行一条记录。
+//   - MIMECSV：首行为表头，之后每行一条记录，不包含分页结果。
+//
+// 获取或编码记录发生错误时，JSON在data之后追加error成员，NDJSON追加一行{"error":{...}}，然后返回该错误。
+// CSV格式中以=、+、-、@开头且不是数字的单元格会加上'前缀，避免被电子表格当作公式执行。
+// w实现了http.Flusher时，每次刷新都会将已写入的数据发送给客户端。
+func EncodeStream[T any](ctx context.Context, w io.Writer, cfg StreamConfig, result Result, page Page, next Iterator[T]) (int, error) {
+	if cfg.FlushEvery <= 0 {
+		cfg.FlushEvery = defaultFlushEvery
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	s := &streamer{w: w, bw: bufio.NewWriterSize(w, streamBufferSize), cfg: cfg, last: time.Now()}
+	var enc streamEncoder
+	switch cfg.Format {
+	case "", MIMEJSON:
+		enc = &jsonStream{}
+	case MIMENDJSON:
+		enc = &jsonStream{lines: true}
+	case MIMECSV:
+		s.cw = csv.NewWriter(s.bw)
+		enc = &csvStream[T]{cw: s.cw}
+	default:
+		return 0, fmt.Errorf("不支持的流式格式[%s]", cfg.Format)
+	}
+	if err := enc.head(s, streamHead{Result: result, Pagination: page}); err != nil {
+		return 0, err
+	}
+	stop := s.autoFlush()
+	defer stop()
+	n := 0
+	for {
+		item, ok, err := next(ctx)
+		if err != nil {
+			stop()
+			if werr := enc.fail(s, AsError(err).Result()); werr == nil {
+				_ = s.flush()
+			}
+			return n, err
+		}
+		if !ok {
+			break
+		}
+		var v interface{} = item
+		if !cfg.Trusted {
+			v = Mask(v)
+		}
+		s.mu.Lock()
+		err = enc.item(s, n, v)
+		s.mu.Unlock()
+		if err != nil {
+			stop()
+			// 记录编码失败时同样追加错误，保证已写出的内容是完整的JSON
+			if werr := enc.fail(s, AsError(err).Result()); werr == nil {
+				_ = s.flush()
+			}
+			return n, err
+		}
+		n++
+		s.mu.Lock()
+		if n%cfg.FlushEvery == 0 || time.Since(s.last) >= cfg.FlushInterval {
+			err = s.flush()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			return n, err
+		}
+	}
+	stop()
+	if err := enc.end(s); err != nil {
+		return n, err
+	}
+	return n, s.flush()
+}
+
+// WriteStream 按请求的Accept头选择JSON、NDJSON或CSV格式，将分页结果逐条写入响应，
+// 获取记录发生错误时响应状态码已经是200，错误按EncodeStream的规则写在结果末尾并记录日志，
+// CSV格式通过X-Result-Code尾部字段返回错误码。记录类型不支持CSV格式时只在JSON和NDJSON之间协商。
+func WriteStream[T any](w http.ResponseWriter, r *http.Request, p *Pagination, next Iterator[T]) error {
+	ctx := r.Context()
+	offers := []string{MIMEJSON, MIMENDJSON}
+	if csvSupported[T]() {
+		offers = append(offers, MIMECSV)
+	}
+	format := Negotiate(r.Header.Get("Accept"), offers...)
+	result := OK()
+	result.Opt = OptFromContext(ctx)
+	contentType := format + "; charset=utf-8"
+	page := NewPage(p)
+	if format == MIMECSV {
+		w.Header().Set("X-Total-Records", strconv.Itoa(page.TotalRecords))
+		w.Header().Set("X-Total-Pages", strconv.Itoa(page.TotalPages))
+		w.Header().Set("X-Page", strconv.Itoa(page.PageNo))
+		w.Header().Set("Trailer", resultCodeTrailer)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	n, err := EncodeStream(ctx, w, StreamConfig{Format: format, Trusted: IsTrusted(ctx)}, result, page, next)
+	if format == MIMECSV {
+		w.Header().Set(resultCodeTrailer, strconv.Itoa(ResultFromError(err).Code))
+	}
+	if err != nil {
+		log.Err(err).Ctx(ctx).Msgf("流式写入[%s %s]在第%d条记录后中断", r.Method, r.URL.Path, n)
+	}
+	return err
+}
+
+type streamer struct {
+	mu   sync.Mutex // 保护写入和刷新，后台定时刷新与写入记录并发进行
+	w    io.Writer
+	bw   *bufio.Writer
+	cw   *csv.Writer // CSV格式时写入bw的csv.Writer，刷新时需要先刷新它的缓冲区
+	cfg  StreamConfig
+	last time.Time
+}
+
+func (s *streamer) flush() error {
+	if s.cw != nil {
+		if s.cw.Flush(); s.cw.Error() != nil {
+			return s.cw.Error()
+		}
+	}
+	if err := s.bw.Flush(); err != nil {
+		return err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	s.last = time.Now()
+	return nil
+}
+
+// autoFlush 启动后台定时刷新，获取记录阻塞时已写入的数据同样按FlushInterval发送给客户端，
+// 返回的stop函数停止刷新并等待后台刷新结束，可以重复调用。刷新失败时bufio.Writer会保留错误，由后续的写入返回。
+func (s *streamer) autoFlush() (stop func()) {
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(s.cfg.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				if time.Since(s.last) >= s.cfg.FlushInterval {
+					_ = s.flush()
+				}
+				s.mu.Unlock()
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-exited
+		})
+	}
+}
+
+// writeJSON 使用池化的缓冲区编码v后写入，不包含末尾的换行符
+func (s *streamer) writeJSON(v interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, v, false); err != nil {
+		return err
+	}
+	_, err := s.bw.Write(buf.Bytes())
+	return err
+}
+
+type streamEncoder interface {
+	head(s *streamer, h streamHead) error
+	item(s *streamer, i int, v interface{}) error
+	fail(s *streamer, r Result) error
+	end(s *streamer) error
+}
+
+type jsonStream struct {
+	lines bool // 为true时使用NDJSON格式
+}
+
+func (j *jsonStream) head(s *streamer, h streamHead) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeJSON(buf, h, false); err != nil {
+		return err
+	}
+	if j.lines {
+		buf.WriteByte('\n')
+	} else {
+		buf.Truncate(buf.Len() - 1) // 去掉末尾的}，继续写入记录集
+		buf.WriteString(`,"data":[`)
+	}
+	_, err := s.bw.Write(buf.Bytes())
+	return err
+}
+
+// item 先编码到缓冲区再写入，编码失败时不会写出不完整的记录
+func (j *jsonStream) item(s *streamer, i int, v interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if i > 0 && !j.lines {
+		buf.WriteByte(',')
+	}
+	if err := encodeJSON(buf, v, false); err != nil {
+		return err
+	}
+	if j.lines {
+		buf.WriteByte('\n')
+	}
+	_, err := s.bw.Write(buf.Bytes())
+	return err
+}
+
+func (j *jsonStream) fail(s *streamer, r Result) error {
+	if !j.lines {
+		_, _ = s.bw.WriteString("],")
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := encodeJSON(buf, streamError{r}, false); err != nil {
+			return err
+		}
+		_, err := s.bw.Write(buf.Bytes()[1:]) // 去掉开头的{，作为结果的成员
+		return err
+	}
+	if err := s.writeJSON(streamError{r}); err != nil {
+		return err
+	}
+	return s.bw.WriteByte('\n')
+}
+
+func (j *jsonStream) end(s *streamer) error {
+	if !j.lines {
+		_, err := s.bw.WriteString("]}")
+		return err
+	}
+	return nil
+}
+
+type csvStream[T any] struct {
+	cw      *csv.Writer
+	columns []csvColumn // 结构体记录的列，nil时使用CSVMarshaler或map的键
+	keys    []string    // map记录的列，取自第一条记录
+	row     []string
+	out     []string // 处理公式注入后实际写入的行
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// csvSupported 判断记录类型能否写为CSV，支持CSVMarshaler、结构体和键为字符串的map
+func csvSupported[T any]() bool {
+	var zero T
+	if _, ok := interface{}(zero).(CSVMarshaler); ok {
+		return true
+	}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct || (t.Kind() == reflect.Map && t.Key().Kind() == reflect.String)
+}
+
+func (c *csvStream[T]) head(s *streamer, _ streamHead) error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if !csvSupported[T]() {
+		return fmt.Errorf("记录类型[%s]不支持CSV格式", t)
+	}
+	var zero T
+	if m, ok := interface{}(zero).(CSVMarshaler); ok {
+		return c.write(m.CSVHeader())
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Map {
+		return nil // 表头取自第一条记录
+	}
+	c.columns = csvColumns(t, nil)
+	header := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		header[i] = col.name
+	}
+	return c.write(header)
+}
+
+func (c *csvStream[T]) item(s *streamer, i int, v interface{}) error {
+	if m, ok := v.(CSVMarshaler); ok {
+		return c.write(m.CSVRecord())
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	c.row = c.row[:0]
+	if rv.Kind() == reflect.Map {
+		if c.keys == nil {
+			for _, k := range rv.MapKeys() {
+				c.keys = append(c.keys, k.String())
+			}
+			sort.Strings(c.keys)
+			if err := c.write(c.keys); err != nil {
+				return err
+			}
+		}
+		for _, k := range c.keys {
+			c.row = append(c.row, csvValue(rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))))
+		}
+		return c.write(c.row)
+	}
+	for _, col := range c.columns {
+		c.row = append(c.row, csvValue(fieldByIndex(rv, col.index)))
+	}
+	return c.write(c.row)
+}
+
+// write 写入一行，写入前对单元格进行公式注入处理，不修改row本身
+func (c *csvStream[T]) write(row []string) error {
+	c.out = c.out[:0]
+	for _, cell := range row {
+		c.out = append(c.out, csvSafe(cell))
+	}
+	if err := c.cw.Write(c.out); err != nil {
+		return err
+	}
+	return c.cw.Error()
+}
+
+// fail CSV格式无法在内容中表示错误，由WriteStream通过X-Result-Code尾部字段告知客户端
+func (c *csvStream[T]) fail(s *streamer, r Result) error {
+	return nil
+}
+
+func (c *csvStream[T]) end(s *streamer) error {
+	return nil
+}
+
+// csvSafe 以=、+、-、@开头的单元格会被电子表格当作公式执行，加上'前缀作为文本处理，负数等数字保持原样
+func csvSafe(cell string) string {
+	if cell == "" || !strings.ContainsRune("=+-@", rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
+
+// csvColumns 按JSON名称生成结构体的列，匿名结构体的字段提升到上级
+func csvColumns(t reflect.Type, prefix []int) []csvColumn {
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		index := append(append([]int{}, prefix...), i)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			columns = append(columns, csvColumns(ft, index)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: index})
+	}
+	return columns
+}
+
+// csvValue 将字段值转换为CSV单元格，基本类型直接格式化，其他类型序列化为JSON
+func csvValue(v reflect.Value) string {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	if !v.Type().Implements(jsonMarshalerType) {
+		switch v.Kind() {
+		case reflect.String:
+			return v.String()
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return fmt.Sprint(v.Interface())
+		}
+	}
+	data, err := Codec().Marshal(v.Interface())
+	if err != nil {
+		return ""
+	}
+	var s string
+	if Codec().Unmarshal(data, &s) == nil {
+		return s // time.Time等序列化为JSON字符串的类型去掉引号
+	}
+	return string(data)
+}
@@ -0,0 +1,200 @@
+package common_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aluka-7/common"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type exportRow struct {
+	Id     int64    `json:"id"`
+	Name   string   `json:"name"`
+	Mobile string   `json:"mobile" mask:"mobile"`
+	Tags   []string `json:"tags"`
+}
+
+func exportRows(n int) []exportRow {
+	rows := make([]exportRow, n)
+	for i := range rows {
+		rows[i] = exportRow{Id: int64(i + 1), Name: "n,\"" + string(rune('a'+i%26)), Mobile: "13600009714", Tags: []string{"x"}}
+	}
+	return rows
+}
+
+// syncBuffer 并发安全的缓冲区，用于观察后台刷新写出的数据
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStream(t *testing.T) {
+	Convey("Test Stream", t, func() {
+		rows := exportRows(250)
+		p := common.NewPagination(1, 250, 0)
+		p.SetTotalRecord(250)
+
+		Convey("Test JSON output matches PageResult", func() {
+			var buf bytes.Buffer
+			result := common.OK()
+			result.Opt = "opt-1"
+			n, err := common.EncodeStream(context.Background(), &buf, common.StreamConfig{}, result, common.NewPage(p), common.SliceIterator(rows))
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 250)
+			pr := common.NewPageResult(p, rows)
+			pr.Opt = "opt-1"
+			So(buf.String(), ShouldEqual, common.Json(pr, false))
+		})
+		Convey("Test empty list", func() {
+			var buf bytes.Buffer
+			_, err := common.EncodeStream(context.Background(), &buf, common.StreamConfig{}, common.OK(), common.NewPage(p), common.SliceIterator([]exportRow{}))
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldEndWith, `"data":[]}`)
+		})
+		Convey("Test NDJSON from channel", func() {
+			ch := make(chan exportRow)
+			go func() {
+				for _, r := range rows[:3] {
+					ch <- r
+				}
+				close(ch)
+			}()
+			var buf bytes.Buffer
+			n, err := common.EncodeStream(context.Background(), &buf, common.StreamConfig{Format: common.MIMENDJSON}, common.OK(), common.NewPage(p), common.ChanIterator(ch))
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 3)
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			So(lines, ShouldHaveLength, 4)
+			So(lines[0], ShouldStartWith, `{"code":200`)
+			So(lines[0], ShouldContainSubstring, `"pagination":{"page":1`)
+			So(lines[1], ShouldEqual, `{"id":1,"name":"n,\"a","mobile":"136****9714","tags":["x"]}`)
+		})
+		Convey("Test CSV rows", func() {
+			var buf bytes.Buffer
+			_, err := common.EncodeStream(context.Background(), &buf, common.StreamConfig{Format: common.MIMECSV}, common.OK(), common.NewPage(p), common.SliceIterator(rows[:2]))
+			So(err, ShouldBeNil)
+			records, err := csv.NewReader(&buf).ReadAll()
+			So(err, ShouldBeNil)
+			So(records, ShouldResemble, [][]string{
+				{"id", "name", "mobile", "tags"},
+				{"1", "n,\"a", "136****9714", `["x"]`},
+				{"2", "n,\"b", "136****9714", `["x"]`},
+			})
+		})
+		Convey("Test iterator error is appended", func() {
+			failing := func() common.Iterator[exportRow] {
+				next := common.SliceIterator(rows[:2])
+				return func(ctx context.Context) (exportRow, bool, error) {
+					r, ok, err := next(ctx)
+					if !ok {
+						return r, false, errors.New("db gone")
+					}
+					return r, ok, err
+				}
+			}
+			var buf bytes.Buffer
+			n, err := common.EncodeStream(context.Background(), &buf, common.StreamConfig{}, common.OK(), common.NewPage(p), failing())
+			So(err, ShouldNotBeNil)
+			So(n, ShouldEqual, 2)
+			So(buf.String(), ShouldEndWith, `],"error":{"code":500,"msg":"服务器内部错误","opt":""}}`)
+
+			buf.Reset()
+			_, err = common.EncodeStream(context.Background(), &buf, common.StreamConfig{Format: common.MIMENDJSON}, common.OK(), common.NewPage(p), failing())
+			So(err, ShouldNotBeNil)
+			So(buf.String(), ShouldEndWith, "{\"error\":{\"code\":500,\"msg\":\"服务器内部错误\",\"opt\":\"\"}}\n")
+		})
+		Convey("Test item encode error keeps JSON valid", func() {
+			var buf bytes.Buffer
+			items := []interface{}{1, func() {}}
+			n, err := common.EncodeStream(context.Background(), &buf, common.StreamConfig{}, common.OK(), common.NewPage(p), common.SliceIterator(items))
+			So(err, ShouldNotBeNil)
+			So(n, ShouldEqual, 1)
+			var v map[string]interface{}
+			So(common.Codec().Unmarshal(buf.Bytes(), &v), ShouldBeNil)
+			So(v["data"], ShouldResemble, []interface{}{float64(1)})
+			So(v["error"], ShouldNotBeNil)
+		})
+		Convey("Test rows are flushed while the iterator blocks", func() {
+			ch := make(chan exportRow)
+			var buf syncBuffer
+			done := make(chan error, 1)
+			go func() {
+				_, err := common.EncodeStream(context.Background(), &buf, common.StreamConfig{Format: common.MIMENDJSON, FlushInterval: 10 * time.Millisecond}, common.OK(), common.NewPage(p), common.ChanIterator(ch))
+				done <- err
+			}()
+			ch <- rows[0]
+			deadline := time.Now().Add(time.Second)
+			for strings.Count(buf.String(), "\n") < 2 && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			So(strings.Count(buf.String(), "\n"), ShouldEqual, 2)
+			close(ch)
+			So(<-done, ShouldBeNil)
+		})
+		Convey("Test CSV formula cells are neutralised", func() {
+			var buf bytes.Buffer
+			cells := []map[string]string{{"a": "=1+2", "b": "-3", "c": "@SUM(A1)", "d": "+x"}}
+			_, err := common.EncodeStream(context.Background(), &buf, common.StreamConfig{Format: common.MIMECSV}, common.OK(), common.NewPage(p), common.SliceIterator(cells))
+			So(err, ShouldBeNil)
+			records, _ := csv.NewReader(&buf).ReadAll()
+			So(records[1], ShouldResemble, []string{"'=1+2", "-3", "'@SUM(A1)", "'+x"})
+		})
+		Convey("Test write stream falls back to JSON when CSV is unsupported", func() {
+			rec := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", "text/csv")
+			So(common.WriteStream(rec, r, p, common.SliceIterator([]int{1, 2})), ShouldBeNil)
+			So(rec.Header().Get("Content-Type"), ShouldEqual, "application/json; charset=utf-8")
+			So(rec.Body.String(), ShouldEndWith, `"data":[1,2]}`)
+		})
+		Convey("Test write stream negotiates and flushes", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = common.WriteStream(w, r, p, common.SliceIterator(rows))
+			}))
+			defer srv.Close()
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+			req.Header.Set("Accept", "text/csv")
+			resp, err := http.DefaultClient.Do(req)
+			So(err, ShouldBeNil)
+			defer resp.Body.Close()
+			So(resp.Header.Get("Content-Type"), ShouldEqual, "text/csv; charset=utf-8")
+			So(resp.Header.Get("X-Total-Records"), ShouldEqual, "250")
+			lines := 0
+			sc := bufio.NewScanner(resp.Body)
+			for sc.Scan() {
+				lines++
+			}
+			So(lines, ShouldEqual, 251)
+			So(resp.Trailer.Get("X-Result-Code"), ShouldEqual, "200")
+
+			rec := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", common.MIMENDJSON)
+			So(common.WriteStream(rec, r, p, common.SliceIterator(rows)), ShouldBeNil)
+			So(rec.Flushed, ShouldBeTrue)
+			So(strings.Count(rec.Body.String(), "\n"), ShouldEqual, 251)
+		})
+	})
+}
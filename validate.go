@@ -0,0 +1,321 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// RuleFunc 校验规则，v为字段的值(指针已解引用)，param为标签中=之后的参数
+type RuleFunc func(v reflect.Value, param string) bool
+
+type rule struct {
+	check   RuleFunc
+	message Code // 校验失败的多语言消息，带参数的规则使用param格式化
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]rule{
+		"min":   {checkMin, newCode(0, "不能小于%s", map[string]string{"en": "must be at least %s"})},
+		"max":   {checkMax, newCode(0, "不能大于%s", map[string]string{"en": "must be at most %s"})},
+		"len":   {checkLen, newCode(0, "长度必须为%s", map[string]string{"en": "must have length %s"})},
+		"email": {checkEmail, newCode(0, "不是有效的邮箱地址", map[string]string{"en": "must be a valid email address"})},
+		"oneof": {checkOneOf, newCode(0, "必须是[%s]中的一个", map[string]string{"en": "must be one of [%s]"})},
+	}
+	requiredMessage = newCode(0, "不能为空", map[string]string{"en": "is required"})
+	typeRules       sync.Map // reflect.Type -> []fieldRules
+)
+
+// RegisterRule 注册自定义校验规则，在结构体字段上使用`validate:"name"`或`validate:"name=param"`引用，重复注册时panic。
+// @param           name            规则名称
+// @param           check           校验函数，返回false表示校验失败
+// @param           message         默认消息，规则带参数时可以使用%s引用参数
+// @param           translations    其他语言的消息，键为语言标签
+func RegisterRule(name string, check RuleFunc, message string, translations map[string]string) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	if _, ok := rules[name]; ok || name == "required" || name == "omitempty" {
+		panic(fmt.Sprintf("校验规则[%s]重复注册", name))
+	}
+	rules[name] = rule{check: check, message: newCode(0, message, translations)}
+}
+
+func lookupRule(name string) (rule, bool) {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	r, ok := rules[name]
+	return r, ok
+}
+
+// FieldError 字段校验失败的信息
+type FieldError struct {
+	Field   string `json:"field"`   // 字段路径，使用JSON名称，如items[0].sku
+	Rule    string `json:"rule"`    // 失败的规则名称
+	Message string `json:"message"` // 按语言偏好生成的消息
+}
+
+// ValidationErrors 参数校验失败的字段列表，通过WriteFail写入时返回Data为该列表的DtoResult，
+// 按其他错误处理时视为ErrCodeBadRequest。
+type ValidationErrors []FieldError
+
+func (ve ValidationErrors) Error() string {
+	parts := make([]string, len(ve))
+	for i, fe := range ve {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Unwrap 返回对应的ErrCodeBadRequest错误，使AsError、ResultFromError以及gRPC拦截器能够识别参数错误
+func (ve ValidationErrors) Unwrap() error {
+	e := NewError(ErrCodeBadRequest)
+	e.Message = fmt.Sprintf("%s[%s]", e.Message, ve.Error())
+	return e
+}
+
+// DtoResult 转换为失败的DtoResult，Data为字段列表，消息按上下文中的语言偏好生成
+func (ve ValidationErrors) DtoResult(ctx context.Context) DtoResult {
+	return DtoResult{Result: FailCtx(ctx, ErrCodeBadRequest), Data: ve}
+}
+
+// Validate 按结构体字段的validate标签校验v，支持嵌套结构体、指针、切片和map，包括pb包生成的结构体。
+// 内置规则：required、min、max、len、email、oneof，omitempty表示字段为零值时跳过其余规则。
+// min、max、len对数字比较数值，对字符串、切片和map比较长度；oneof的参数使用空格分隔。
+// 校验通过时返回nil，否则返回ValidationErrors，消息使用上下文中的语言偏好。
+func Validate(ctx context.Context, v interface{}) error {
+	var errs ValidationErrors
+	validateValue(reflect.ValueOf(v), "", LanguagesFromContext(ctx), &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// DecodeRequest 将请求体的JSON解码到v并校验，解码失败返回ErrCodeBadRequest，校验失败返回ValidationErrors
+func DecodeRequest(r *http.Request, v interface{}) error {
+	if err := decodeJSON(r.Body, v); err != nil {
+		return WrapError(err, ErrCodeBadRequest)
+	}
+	return Validate(r.Context(), v)
+}
+
+type fieldRules struct {
+	index     []int
+	name      string
+	required  bool
+	omitempty bool
+	rules     []ruleRef
+	dive      bool // 字段类型可能包含需要校验的结构体
+}
+
+type ruleRef struct {
+	name, param string
+}
+
+func validateValue(v reflect.Value, path string, langs []string, errs *ValidationErrors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, fr := range rulesOf(v.Type()) {
+			fv := fieldByIndex(v, fr.index)
+			name := fr.name
+			if path != "" {
+				name = path + "." + name
+			}
+			if fv.IsValid() {
+				validateField(fv, fr, name, langs, errs)
+			}
+			if fr.dive && fv.IsValid() {
+				validateValue(fv, name, langs, errs)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if !mayContainStruct(v.Type().Elem()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), langs, errs)
+		}
+	case reflect.Map:
+		if !mayContainStruct(v.Type().Elem()) {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			validateValue(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), langs, errs)
+		}
+	}
+}
+
+func validateField(fv reflect.Value, fr fieldRules, name string, langs []string, errs *ValidationErrors) {
+	if fv.IsZero() {
+		if fr.required {
+			*errs = append(*errs, FieldError{Field: name, Rule: "required", Message: requiredMessage.Message(langs)})
+			return
+		}
+		if fr.omitempty {
+			return
+		}
+	}
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+	for _, ref := range fr.rules {
+		r, _ := lookupRule(ref.name)
+		if r.check(fv, ref.param) {
+			continue
+		}
+		msg := r.message.localize(langs)
+		if strings.Contains(msg, "%s") {
+			msg = fmt.Sprintf(msg, ref.param)
+		}
+		*errs = append(*errs, FieldError{Field: name, Rule: ref.name, Message: msg})
+	}
+}
+
+// rulesOf 解析并缓存结构体的校验规则，标签中引用未注册的规则时panic
+func rulesOf(t reflect.Type) []fieldRules {
+	if v, ok := typeRules.Load(t); ok {
+		return v.([]fieldRules)
+	}
+	frs := compileRules(t, nil)
+	typeRules.Store(t, frs)
+	return frs
+}
+
+func compileRules(t reflect.Type, prefix []int) []fieldRules {
+	var frs []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		index := append(append([]int{}, prefix...), i)
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && jsonName == "" && ft.Kind() == reflect.Struct && f.Tag.Get("validate") == "" {
+			frs = append(frs, compileRules(ft, index)...)
+			continue
+		}
+		if f.PkgPath != "" || jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = f.Name
+		}
+		fr := fieldRules{index: index, name: jsonName, dive: mayContainStruct(f.Type)}
+		for _, part := range strings.Split(f.Tag.Get("validate"), ",") {
+			name, param := part, ""
+			if j := strings.IndexByte(part, '='); j >= 0 {
+				name, param = part[:j], part[j+1:]
+			}
+			switch name = strings.TrimSpace(name); name {
+			case "":
+			case "required":
+				fr.required = true
+			case "omitempty":
+				fr.omitempty = true
+			default:
+				if _, ok := lookupRule(name); !ok {
+					panic(fmt.Sprintf("字段[%s.%s]使用了未注册的校验规则[%s]", t, f.Name, name))
+				}
+				fr.rules = append(fr.rules, ruleRef{name, param})
+			}
+		}
+		if fr.required || len(fr.rules) > 0 || fr.dive {
+			frs = append(frs, fr)
+		}
+	}
+	return frs
+}
+
+// mayContainStruct 判断类型是否可能包含需要递归校验的结构体
+func mayContainStruct(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return mayContainStruct(t.Elem())
+	case reflect.Struct, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// size 返回比较min、max、len时使用的值，数字为数值，字符串为字符数，切片和map为长度
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func compare(v reflect.Value, param string, ok func(n, p float64) bool) bool {
+	p, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return false
+	}
+	n, valid := size(v)
+	return valid && ok(n, p)
+}
+
+func checkMin(v reflect.Value, param string) bool {
+	return compare(v, param, func(n, p float64) bool { return n >= p })
+}
+
+func checkMax(v reflect.Value, param string) bool {
+	return compare(v, param, func(n, p float64) bool { return n <= p })
+}
+
+func checkLen(v reflect.Value, param string) bool {
+	return compare(v, param, func(n, p float64) bool { return n == p })
+}
+
+func checkEmail(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(v.String())
+	return err == nil && addr.Address == v.String()
+}
+
+func checkOneOf(v reflect.Value, param string) bool {
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = fmt.Sprint(v.Interface())
+	default:
+		return false
+	}
+	for _, option := range strings.Fields(param) {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package common_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aluka-7/common"
+	"github.com/aluka-7/common/pb"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type signupItem struct {
+	Sku string `json:"sku" validate:"required,len=6"`
+	Qty int    `json:"qty" validate:"min=1,max=99"`
+}
+
+type signupRequest struct {
+	Name   string       `json:"name" validate:"required,max=4"`
+	Email  string       `json:"email" validate:"omitempty,email"`
+	Gender string       `json:"gender" validate:"oneof=male female"`
+	Phone  *string      `json:"phone" validate:"omitempty,mobile"`
+	Items  []signupItem `json:"items" validate:"required"`
+	Extra  *signupItem  `json:"extra"`
+}
+
+func init() {
+	common.RegisterRule("mobile", func(v reflect.Value, _ string) bool {
+		return v.Kind() == reflect.String && len(v.String()) == 11 && strings.HasPrefix(v.String(), "1")
+	}, "不是有效的手机号", map[string]string{"en": "must be a valid mobile number"})
+}
+
+func TestValidate(t *testing.T) {
+	Convey("Test Validate", t, func() {
+		ctx := context.Background()
+		phone := "12345"
+
+		Convey("Test valid request", func() {
+			req := signupRequest{Name: "张三", Email: "a@b.cn", Gender: "male", Items: []signupItem{{Sku: "ABC123", Qty: 1}}}
+			So(common.Validate(ctx, &req), ShouldBeNil)
+		})
+		Convey("Test field errors", func() {
+			req := signupRequest{Name: "abcdef", Email: "bad", Phone: &phone, Items: []signupItem{{Sku: "A", Qty: 100}}, Extra: &signupItem{Sku: "ABC123"}}
+			err := common.Validate(ctx, req)
+			var ve common.ValidationErrors
+			So(errors.As(err, &ve), ShouldBeTrue)
+			So(ve, ShouldResemble, common.ValidationErrors{
+				{Field: "name", Rule: "max", Message: "不能大于4"},
+				{Field: "email", Rule: "email", Message: "不是有效的邮箱地址"},
+				{Field: "gender", Rule: "oneof", Message: "必须是[male female]中的一个"},
+				{Field: "phone", Rule: "mobile", Message: "不是有效的手机号"},
+				{Field: "items[0].sku", Rule: "len", Message: "长度必须为6"},
+				{Field: "items[0].qty", Rule: "max", Message: "不能大于99"},
+				{Field: "extra.qty", Rule: "min", Message: "不能小于1"},
+			})
+			So(common.ResultFromError(err).Code, ShouldEqual, common.ErrCodeBadRequest.Code())
+		})
+		Convey("Test messages follow language preference", func() {
+			err := common.Validate(common.WithLanguages(ctx, []string{"en-US"}), signupRequest{Gender: "male"})
+			So(err.(common.ValidationErrors)[0], ShouldResemble, common.FieldError{Field: "name", Rule: "required", Message: "is required"})
+		})
+		Convey("Test generated pb structs", func() {
+			err := common.Validate(ctx, &pb.Query{PageSize: 10})
+			So(err, ShouldResemble, common.ValidationErrors{{Field: "page", Rule: "required", Message: "不能为空"}})
+			So(common.Validate(ctx, &pb.Query{PageSize: 10, Page: 1}), ShouldBeNil)
+		})
+		Convey("Test write fail returns field list", func() {
+			r := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(`{"name":"","gender":"male","items":[{"sku":"ABC123","qty":1}]}`))
+			var req signupRequest
+			err := common.DecodeRequest(r, &req)
+			So(err, ShouldNotBeNil)
+			rec := httptest.NewRecorder()
+			So(common.WriteFail(rec, r, err), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusBadRequest)
+			dto, err := common.DecodeDto[common.ValidationErrors](rec.Body)
+			So(err, ShouldBeNil)
+			So(dto.Code, ShouldEqual, common.ErrCodeBadRequest.Code())
+			So(dto.Data, ShouldResemble, common.ValidationErrors{{Field: "name", Rule: "required", Message: "不能为空"}})
+
+			r = httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(`{`))
+			So(errors.Is(common.DecodeRequest(r, &req), common.NewError(common.ErrCodeBadRequest)), ShouldBeTrue)
+		})
+		Convey("Test unknown rule panics", func() {
+			type bad struct {
+				Name string `validate:"nosuchrule"`
+			}
+			So(func() { _ = common.Validate(ctx, bad{}) }, ShouldPanic)
+		})
+	})
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aluka-7/common"
+	"github.com/rs/zerolog/log"
+)
+
+// Endpoint 回调的接收地址
+type Endpoint struct {
+	URL         string // 接收地址
+	Secret      []byte // 签名密钥，与接收方约定
+	Concurrency int    // 对该地址同时进行的最大投递数，小于等于0时为1
+}
+
+// DeadLetter 重试耗尽后仍投递失败的回调
+type DeadLetter struct {
+	URL       string          `json:"url"`       // 接收地址
+	Callback  common.Callback `json:"callback"`  // 回调内容
+	Attempts  int             `json:"attempts"`  // 已尝试的次数
+	LastError string          `json:"lastError"` // 最后一次失败的原因
+	FailedAt  time.Time       `json:"failedAt"`  // 放入死信队列的时间
+}
+
+// DeadLetterQueue 死信队列，保存投递失败的回调以便人工处理或稍后重放
+type DeadLetterQueue interface {
+	Push(ctx context.Context, dl DeadLetter) error
+}
+
+// MemoryDeadLetterQueue 基于内存的死信队列，适用于测试和单实例部署
+type MemoryDeadLetterQueue struct {
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+func (q *MemoryDeadLetterQueue) Push(ctx context.Context, dl DeadLetter) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.letters = append(q.letters, dl)
+	return nil
+}
+
+// Drain 取出并清空队列中的所有死信
+func (q *MemoryDeadLetterQueue) Drain() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	letters := q.letters
+	q.letters = nil
+	return letters
+}
+
+// Dispatcher 回调投递器，创建后可以调整导出字段，开始投递后不应再修改。
+// 零值可以直接使用，此时只尝试一次，Client为nil时使用超时10秒的默认客户端。
+type Dispatcher struct {
+	Client      *http.Client    // 投递使用的HTTP客户端
+	MaxAttempts int             // 最大尝试次数(包括第一次)
+	Backoff     time.Duration   // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff  time.Duration   // 重试等待时间的上限
+	DeadLetters DeadLetterQueue // 死信队列，为nil时丢弃并记录日志
+
+	mu   sync.Mutex
+	sems map[string]chan struct{} // 每个接收地址的并发令牌
+}
+
+// NewDispatcher 创建回调投递器，默认最多尝试5次，重试等待从1秒开始翻倍，最长1分钟
+func NewDispatcher(dlq DeadLetterQueue) *Dispatcher {
+	return &Dispatcher{
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: 5,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		DeadLetters: dlq,
+	}
+}
+
+// permanentError 不需要重试的错误，如接收方返回4xx
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Deliver 同步投递回调，失败时按指数退避重试，重试耗尽或遇到不可重试的错误时放入死信队列并返回最后一次的错误。
+// 回调没有操作编号时使用上下文中的操作编号，上下文中也没有时生成新编号。
+func (d *Dispatcher) Deliver(ctx context.Context, ep Endpoint, cb common.Callback) error {
+	if cb.Opt == "" {
+		if cb.Opt = common.OptFromContext(ctx); cb.Opt == "" {
+			cb.Opt = common.NewOpt()
+		}
+	}
+	body, err := json.Marshal(cb)
+	if err != nil {
+		return err
+	}
+	attempt := 0
+	for {
+		attempt++
+		err = d.post(ctx, ep, cb.Opt, body)
+		if err == nil {
+			return nil
+		}
+		if _, permanent := err.(permanentError); permanent || attempt >= d.MaxAttempts || ctx.Err() != nil {
+			break
+		}
+		log.Warn().Ctx(ctx).Err(err).Msgf("回调投递失败[%s]，第%d次重试", ep.URL, attempt)
+		timer := time.NewTimer(d.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+	dl := DeadLetter{URL: ep.URL, Callback: cb, Attempts: attempt, LastError: err.Error(), FailedAt: time.Now()}
+	if d.DeadLetters == nil {
+		log.Error().Ctx(ctx).Err(err).Msgf("回调投递失败[%s]，已丢弃: %s", ep.URL, body)
+	} else if qerr := d.DeadLetters.Push(context.Background(), dl); qerr != nil {
+		log.Error().Ctx(ctx).Err(qerr).Msgf("回调放入死信队列失败[%s]: %s", ep.URL, body)
+	}
+	return err
+}
+
+// Go 在后台投递回调，不等待投递结果
+func (d *Dispatcher) Go(ctx context.Context, ep Endpoint, cb common.Callback) {
+	ctx = common.WithOpt(context.Background(), common.OptFromContext(ctx))
+	go func() {
+		_ = d.Deliver(ctx, ep, cb)
+	}()
+}
+
+// backoff 计算第attempt次失败后的等待时间，在指数退避的基础上随机抖动，避免大量回调同时重试
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.Backoff << uint(attempt-1)
+	if wait <= 0 || wait > d.MaxBackoff {
+		wait = d.MaxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func (d *Dispatcher) post(ctx context.Context, ep Endpoint, opt string, body []byte) error {
+	sem, client := d.resources(ep)
+	select {
+	case sem <- struct{}{}:
+		defer func() { <-sem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, Sign(ep.Secret, ts, body))
+	req.Header.Set(common.OptHeader, opt)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("接收方返回状态码%d", resp.StatusCode)
+	}
+	return permanentError{fmt.Errorf("接收方返回状态码%d", resp.StatusCode)}
+}
+
+// resources 返回接收地址的并发令牌和投递使用的HTTP客户端，零值的Dispatcher在此延迟初始化。
+// 令牌按接收地址保存，并发数以最近一次投递的Endpoint为准。
+func (d *Dispatcher) resources(ep Endpoint) (chan struct{}, *http.Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.Client == nil {
+		d.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if d.sems == nil {
+		d.sems = make(map[string]chan struct{})
+	}
+	n := ep.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+	// 同一地址的并发数变更时使用新的令牌，进行中的投递仍归还到旧的令牌，变更期间的并发数可能短暂超出
+	sem, ok := d.sems[ep.URL]
+	if !ok || cap(sem) != n {
+		sem = make(chan struct{}, n)
+		d.sems[ep.URL] = sem
+	}
+	return sem, d.Client
+}
@@ -0,0 +1,123 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aluka-7/common"
+)
+
+var (
+	ErrMissingSignature = errors.New("缺少回调签名")
+	ErrInvalidSignature = errors.New("回调签名错误")
+	ErrStaleTimestamp   = errors.New("回调时间戳超出允许范围")
+	ErrReplayed         = errors.New("回调重复请求")
+)
+
+// maxBodySize 接收回调时允许的最大请求体
+const maxBodySize = 1 << 20
+
+// ReplayCache 记录已处理过的签名，用于拒绝重放请求，多实例部署时需要使用共享存储实现
+type ReplayCache interface {
+	// Seen 记录签名并返回此前是否已经出现过，until之后该记录可以被清理
+	Seen(signature string, until time.Time) bool
+}
+
+// replaySweepInterval 内存实现清理过期签名的最小间隔
+const replaySweepInterval = time.Minute
+
+type memoryReplayCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time // 上次清理过期签名的时间
+}
+
+// Seen 过期签名的清理按replaySweepInterval间隔进行，避免每次校验都遍历所有记录
+func (c *memoryReplayCache) Seen(signature string, until time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.lastSweep) >= replaySweepInterval {
+		for k, t := range c.seen {
+			if now.After(t) {
+				delete(c.seen, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	if t, ok := c.seen[signature]; ok && !now.After(t) {
+		return true
+	}
+	c.seen[signature] = until
+	return false
+}
+
+// Verifier 接收方的回调校验器
+type Verifier struct {
+	secret    []byte
+	tolerance time.Duration
+	replay    ReplayCache
+}
+
+// NewVerifier 创建回调校验器，tolerance为允许的时间戳偏差，小于等于0时为5分钟，replay为nil时使用内存实现
+func NewVerifier(secret []byte, tolerance time.Duration, replay ReplayCache) *Verifier {
+	if tolerance <= 0 {
+		tolerance = 5 * time.Minute
+	}
+	if replay == nil {
+		replay = &memoryReplayCache{seen: make(map[string]time.Time)}
+	}
+	return &Verifier{secret: secret, tolerance: tolerance, replay: replay}
+}
+
+// Verify 校验回调请求的时间戳和签名并拒绝重放，通过后返回解码的回调内容
+func (v *Verifier) Verify(r *http.Request) (cb common.Callback, err error) {
+	sig, tsHeader := r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader)
+	if sig == "" || tsHeader == "" {
+		return cb, ErrMissingSignature
+	}
+	ts, err := strconv.ParseInt(tsHeader, 10, 64)
+	if err != nil {
+		return cb, ErrMissingSignature
+	}
+	signedAt := time.Unix(ts, 0)
+	if d := time.Since(signedAt); d > v.tolerance || d < -v.tolerance {
+		return cb, ErrStaleTimestamp
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		return cb, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if !hmac.Equal([]byte(sig), []byte(Sign(v.secret, ts, body))) {
+		return cb, ErrInvalidSignature
+	}
+	if v.replay.Seen(sig, signedAt.Add(v.tolerance)) {
+		return cb, ErrReplayed
+	}
+	err = json.Unmarshal(body, &cb)
+	return cb, err
+}
+
+// Handler 返回接收回调的http.Handler，校验通过后调用fn处理，校验失败返回401，fn返回错误时按common.WriteFail写入
+func (v *Verifier) Handler(fn func(r *http.Request, cb common.Callback) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cb, err := v.Verify(r)
+		if err != nil {
+			_ = common.WriteFail(w, r, common.WrapError(err, common.ErrCodeUnauthorized))
+			return
+		}
+		if err := fn(r, cb); err != nil {
+			_ = common.WriteFail(w, r, err)
+			return
+		}
+		_ = common.WriteResult(w, r, common.OK())
+	})
+}
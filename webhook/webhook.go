@@ -0,0 +1,27 @@
+// Package webhook 回调(common.Callback)的签名投递和接收校验。
+//
+// 发送方使用Dispatcher对回调内容进行HMAC-SHA256签名后POST到接收方，失败时按指数退避重试，
+// 重试耗尽后放入死信队列；接收方使用Verifier校验签名和时间戳并拒绝重放的请求。
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature" // 签名头，格式为v1=<hex>
+	TimestampHeader = "X-Webhook-Timestamp" // 签名时间戳头，Unix秒
+	signatureScheme = "v1="
+)
+
+// Sign 计算回调内容的签名，签名内容为"时间戳.请求体"，返回值即SignatureHeader的值
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return signatureScheme + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,152 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aluka-7/common"
+	"github.com/aluka-7/common/webhook"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWebhook(t *testing.T) {
+	Convey("Test Webhook", t, func() {
+		secret := []byte("webhook-secret")
+		dlq := &webhook.MemoryDeadLetterQueue{}
+		d := webhook.NewDispatcher(dlq)
+		d.Backoff, d.MaxBackoff = time.Millisecond, 5*time.Millisecond
+		cb := common.NewCallBack(1, map[string]int{"orderId": 7})
+
+		Convey("Test signed delivery is verified by receiver", func() {
+			var received common.Callback
+			v := webhook.NewVerifier(secret, time.Minute, nil)
+			srv := httptest.NewServer(v.Handler(func(r *http.Request, cb common.Callback) error {
+				received = cb
+				return nil
+			}))
+			defer srv.Close()
+			So(d.Deliver(context.Background(), webhook.Endpoint{URL: srv.URL, Secret: secret}, cb), ShouldBeNil)
+			So(received.Data, ShouldEqual, cb.Data)
+			So(received.Opt, ShouldNotBeEmpty)
+		})
+		Convey("Test retry then dead letter", func() {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+			d.MaxAttempts = 3
+			So(d.Deliver(context.Background(), webhook.Endpoint{URL: srv.URL, Secret: secret}, cb), ShouldNotBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 3)
+			letters := dlq.Drain()
+			So(len(letters), ShouldEqual, 1)
+			So(letters[0].Attempts, ShouldEqual, 3)
+		})
+		Convey("Test client errors are not retried", func() {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(http.StatusBadRequest)
+			}))
+			defer srv.Close()
+			So(d.Deliver(context.Background(), webhook.Endpoint{URL: srv.URL, Secret: secret}, cb), ShouldNotBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+			So(len(dlq.Drain()), ShouldEqual, 1)
+		})
+		Convey("Test per endpoint concurrency", func() {
+			var current, peak int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&current, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&current, -1)
+			}))
+			defer srv.Close()
+			ep := webhook.Endpoint{URL: srv.URL, Secret: secret, Concurrency: 2}
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_ = d.Deliver(context.Background(), ep, cb)
+				}()
+			}
+			wg.Wait()
+			So(atomic.LoadInt32(&peak), ShouldBeLessThanOrEqualTo, 2)
+		})
+		Convey("Test zero value dispatcher delivers", func() {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+			}))
+			defer srv.Close()
+			var zero webhook.Dispatcher
+			So(zero.Deliver(context.Background(), webhook.Endpoint{URL: srv.URL, Secret: secret}, cb), ShouldBeNil)
+			So(atomic.LoadInt32(&calls), ShouldEqual, 1)
+		})
+		Convey("Test concurrency limit follows the latest endpoint", func() {
+			release := make(chan struct{})
+			var current int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&current, 1)
+				<-release
+				atomic.AddInt32(&current, -1)
+			}))
+			defer srv.Close()
+			go func() { release <- struct{}{} }()
+			So(d.Deliver(context.Background(), webhook.Endpoint{URL: srv.URL, Secret: secret, Concurrency: 1}, cb), ShouldBeNil)
+
+			ep := webhook.Endpoint{URL: srv.URL, Secret: secret, Concurrency: 3}
+			done := make(chan struct{}, 3)
+			for i := 0; i < cap(done); i++ {
+				go func() {
+					_ = d.Deliver(context.Background(), ep, cb)
+					done <- struct{}{}
+				}()
+			}
+			deadline := time.Now().Add(time.Second)
+			for atomic.LoadInt32(&current) < 3 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			So(atomic.LoadInt32(&current), ShouldEqual, 3)
+			close(release)
+			for i := 0; i < cap(done); i++ {
+				<-done
+			}
+		})
+		Convey("Test verifier rejects tampering, stale and replayed requests", func() {
+			v := webhook.NewVerifier(secret, time.Minute, nil)
+			body := []byte(common.Json(cb, false))
+			newReq := func(ts int64, body []byte, sig string) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader(body))
+				r.Header.Set(webhook.TimestampHeader, strconv.FormatInt(ts, 10))
+				r.Header.Set(webhook.SignatureHeader, sig)
+				return r
+			}
+			now := time.Now().Unix()
+			sig := webhook.Sign(secret, now, body)
+			_, err := v.Verify(newReq(now, body, sig))
+			So(err, ShouldBeNil)
+			_, err = v.Verify(newReq(now, body, sig))
+			So(err, ShouldEqual, webhook.ErrReplayed)
+			_, err = v.Verify(newReq(now, []byte(`{"cbType":2}`), sig))
+			So(err, ShouldEqual, webhook.ErrInvalidSignature)
+			old := now - 3600
+			_, err = v.Verify(newReq(old, body, webhook.Sign(secret, old, body)))
+			So(err, ShouldEqual, webhook.ErrStaleTimestamp)
+		})
+	})
+}